@@ -23,7 +23,7 @@ func (a *AstPrinter) PrintStmt(stmt parser.Stmt) string {
 }
 
 func (a *AstPrinter) VisitBlockStmt(stmt *parser.BlockStmt) interface{} {
-	return stmt.Accept(a)
+	return a.parenthesizeParts("block", stmt.Statements)
 }
 
 func (a *AstPrinter) VisitLiteralExpr(expr *parser.Literal) interface{} {
@@ -54,6 +54,69 @@ func (a *AstPrinter) VisitBinaryExpr(expr *parser.Binary) interface{} {
 	return a.parenthesize(expr.Operator.Lexeme, expr.Left, expr.Right)
 }
 
+func (a *AstPrinter) VisitConditionalExpr(expr *parser.Conditional) interface{} {
+	return a.parenthesize("?:", expr.Condition, expr.ThenBranch, expr.ElseBranch)
+}
+
+func (a *AstPrinter) VisitLogicalExpr(expr *parser.Logical) interface{} {
+	return a.parenthesize(expr.Operator.Lexeme, expr.Left, expr.Right)
+}
+
+func (a *AstPrinter) VisitCallExpr(expr *parser.Call) interface{} {
+	return a.parenthesize("call", append([]parser.Expr{expr.Callee}, expr.Arguments...)...)
+}
+
+func (a *AstPrinter) VisitGetExpr(expr *parser.Get) interface{} {
+	return a.parenthesizeParts(".", expr.Object, expr.Name.Lexeme)
+}
+
+func (a *AstPrinter) VisitSetExpr(expr *parser.Set) interface{} {
+	return a.parenthesizeParts("=", expr.Object, expr.Name.Lexeme, expr.Value)
+}
+
+func (a *AstPrinter) VisitListExpr(expr *parser.List) interface{} {
+	return a.parenthesize("list", expr.Elements...)
+}
+
+func (a *AstPrinter) VisitMapExpr(expr *parser.Map) interface{} {
+	parts := make([]interface{}, len(expr.Keys))
+	for i, key := range expr.Keys {
+		parts[i] = a.parenthesize(":", key, expr.Values[i])
+	}
+	return a.parenthesizeParts("map", parts...)
+}
+
+func (a *AstPrinter) VisitInterpolationExpr(expr *parser.Interpolation) interface{} {
+	return a.parenthesize("interpolate", expr.Parts...)
+}
+
+func (a *AstPrinter) VisitIndexExpr(expr *parser.Index) interface{} {
+	return a.parenthesize("[]", expr.Object, expr.Index)
+}
+
+func (a *AstPrinter) VisitIndexSetExpr(expr *parser.IndexSet) interface{} {
+	return a.parenthesize("[]=", expr.Object, expr.Index, expr.Value)
+}
+
+func (a *AstPrinter) VisitSuperExpr(expr *parser.Super) interface{} {
+	return a.parenthesizeParts("super", expr.Method.Lexeme)
+}
+
+func (a *AstPrinter) VisitThisExpr(expr *parser.This) interface{} {
+	return "this"
+}
+
+func (a *AstPrinter) VisitCompoundAssignExpr(expr *parser.CompoundAssign) interface{} {
+	return a.parenthesize(expr.Operator.Lexeme, expr.Target, expr.Value)
+}
+
+func (a *AstPrinter) VisitIncrementExpr(expr *parser.Increment) interface{} {
+	if expr.Prefix {
+		return a.parenthesize("pre"+expr.Operator.Lexeme, expr.Target)
+	}
+	return a.parenthesize("post"+expr.Operator.Lexeme, expr.Target)
+}
+
 // New method to implement
 func (a *AstPrinter) VisitAssignExpr(expr *parser.Assign) interface{} {
 	return a.parenthesize("assign "+expr.Name.Lexeme, expr.Value)
@@ -79,6 +142,64 @@ func (a *AstPrinter) VisitVarStmt(stmt *parser.VarStmt) interface{} {
 	return a.parenthesize("var " + stmt.Name.Lexeme)
 }
 
+func (a *AstPrinter) VisitIfStmt(stmt *parser.IfStmt) interface{} {
+	if stmt.ElseBranch == nil {
+		return a.parenthesizeParts("if", stmt.Condition, stmt.ThenBranch)
+	}
+	return a.parenthesizeParts("if-else", stmt.Condition, stmt.ThenBranch, stmt.ElseBranch)
+}
+
+func (a *AstPrinter) VisitWhileStmt(stmt *parser.WhileStmt) interface{} {
+	name := "while"
+	if stmt.Label != nil {
+		name = stmt.Label.Lexeme + ": while"
+	}
+	if stmt.Increment != nil {
+		return a.parenthesizeParts(name, stmt.Condition, stmt.Body, stmt.Increment)
+	}
+	return a.parenthesizeParts(name, stmt.Condition, stmt.Body)
+}
+
+func (a *AstPrinter) VisitBreakStmt(stmt *parser.BreakStmt) interface{} {
+	if stmt.Label != nil {
+		return a.parenthesizeParts("break", stmt.Label.Lexeme)
+	}
+	return "(break)"
+}
+
+func (a *AstPrinter) VisitContinueStmt(stmt *parser.ContinueStmt) interface{} {
+	if stmt.Label != nil {
+		return a.parenthesizeParts("continue", stmt.Label.Lexeme)
+	}
+	return "(continue)"
+}
+
+func (a *AstPrinter) VisitFunctionStmt(stmt *parser.FunctionStmt) interface{} {
+	params := make([]string, len(stmt.Params))
+	for i, param := range stmt.Params {
+		params[i] = param.Lexeme
+	}
+	return a.parenthesizeParts("fun "+stmt.Name.Lexeme, "("+strings.Join(params, " ")+")", stmt.Body)
+}
+
+func (a *AstPrinter) VisitClassStmt(stmt *parser.ClassStmt) interface{} {
+	methods := make([]parser.Stmt, len(stmt.Methods))
+	for i, method := range stmt.Methods {
+		methods[i] = method
+	}
+	if stmt.Superclass != nil {
+		return a.parenthesizeParts("class "+stmt.Name.Lexeme+" < "+stmt.Superclass.Name.Lexeme, methods)
+	}
+	return a.parenthesizeParts("class "+stmt.Name.Lexeme, methods)
+}
+
+func (a *AstPrinter) VisitReturnStmt(stmt *parser.ReturnStmt) interface{} {
+	if stmt.Value == nil {
+		return "(return)"
+	}
+	return a.parenthesize("return", stmt.Value)
+}
+
 func (a *AstPrinter) parenthesize(name string, exprs ...parser.Expr) string {
 	var builder strings.Builder
 	builder.WriteString("(")
@@ -90,3 +211,39 @@ func (a *AstPrinter) parenthesize(name string, exprs ...parser.Expr) string {
 	builder.WriteString(")")
 	return builder.String()
 }
+
+// parenthesizeParts is like parenthesize but also accepts statements, statement
+// lists and plain strings, which is what the statement visitors need.
+func (a *AstPrinter) parenthesizeParts(name string, parts ...interface{}) string {
+	var builder strings.Builder
+	builder.WriteString("(")
+	builder.WriteString(name)
+	for _, part := range parts {
+		// An empty body or method list adds nothing, not even the separator.
+		if stmts, ok := part.([]parser.Stmt); ok && len(stmts) == 0 {
+			continue
+		}
+		builder.WriteString(" ")
+		builder.WriteString(a.printPart(part))
+	}
+	builder.WriteString(")")
+	return builder.String()
+}
+
+func (a *AstPrinter) printPart(part interface{}) string {
+	switch p := part.(type) {
+	case parser.Expr:
+		return p.Accept(a).(string)
+	case parser.Stmt:
+		return p.Accept(a).(string)
+	case []parser.Stmt:
+		parts := make([]string, len(p))
+		for i, stmt := range p {
+			parts[i] = stmt.Accept(a).(string)
+		}
+		return strings.Join(parts, " ")
+	case string:
+		return p
+	}
+	return fmt.Sprintf("%v", part)
+}
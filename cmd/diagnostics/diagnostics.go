@@ -0,0 +1,232 @@
+package diagnostics
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"unicode/utf8"
+)
+
+type Kind int
+
+const (
+	// KindStatic covers errors found before the program runs: by the scanner,
+	// the parser or the resolver.
+	KindStatic Kind = iota
+	KindRuntime
+)
+
+// Span is a region of the source, in the same terms as scanner.Token: Line is
+// the line the span ends on, which the header reports, while Column and Offset
+// are where it starts.
+type Span struct {
+	Line   int
+	Column int
+	Offset int
+	Length int
+}
+
+// Diagnostic is an error located in the source. Its Error method gives the
+// classic one-line form; Renderer adds the source snippet. The passes in this
+// module always set a Span, but a reporter may be handed a Diagnostic with a
+// zero Span, which is rendered without a snippet.
+type Diagnostic struct {
+	Kind Kind
+	Span Span
+	// Where names the offending token, as in " at 'x'" or " at end". It is
+	// empty for scanner and runtime errors.
+	Where   string
+	Message string
+	// Help is an optional note printed under the snippet.
+	Help string
+	// Trace lists the calls active when a runtime error happened, innermost
+	// first, one line each.
+	Trace []string
+}
+
+func (e *Diagnostic) Error() string {
+	if e.Kind == KindRuntime {
+		if e.Span.Line == 0 {
+			return e.Message
+		}
+		return fmt.Sprintf("[line %d]%s", e.Span.Line, e.Message)
+	}
+	return fmt.Sprintf("[line %d] Error%s: %s", e.Span.Line, e.Where, e.Message)
+}
+
+// Errors collects every error reported by a pass that keeps going after the
+// first one.
+type Errors []*Diagnostic
+
+func (es Errors) Error() string {
+	var builder strings.Builder
+	for _, err := range es {
+		builder.WriteString(err.Error())
+		builder.WriteString("\n")
+	}
+	return builder.String()
+}
+
+const (
+	ansiReset = "\x1b[0m"
+	ansiRed   = "\x1b[1;31m"
+	ansiBlue  = "\x1b[1;34m"
+	ansiCyan  = "\x1b[1;36m"
+)
+
+// Renderer formats errors rustc-style: the one-line message, then the source
+// line with the offending span underlined, then any help note.
+type Renderer struct {
+	source string
+	color  bool
+}
+
+func NewRenderer(source string, color bool) *Renderer {
+	return &Renderer{source: source, color: color}
+}
+
+// UseColor reports whether output written to f should be coloured: f must be a
+// terminal and NO_COLOR must be unset.
+func UseColor(f *os.File) bool {
+	if os.Getenv("NO_COLOR") != "" {
+		return false
+	}
+	info, err := f.Stat()
+	if err != nil {
+		return false
+	}
+	return info.Mode()&os.ModeCharDevice != 0
+}
+
+func (r *Renderer) Render(err *Diagnostic) string {
+	var builder strings.Builder
+	builder.WriteString(r.paint(ansiRed, err.Error()))
+	builder.WriteString("\n")
+
+	// Notes line up with the gutter, or sit just indented without one.
+	indent := 1
+	if err.Span.Line != 0 {
+		indent = r.writeSnippet(&builder, err.Span)
+	}
+
+	if err.Help != "" {
+		builder.WriteString(strings.Repeat(" ", indent))
+		builder.WriteString(r.paint(ansiBlue, "="))
+		builder.WriteString(" ")
+		builder.WriteString(r.paint(ansiCyan, "help"))
+		builder.WriteString(": ")
+		builder.WriteString(err.Help)
+		builder.WriteString("\n")
+	}
+
+	for _, line := range err.Trace {
+		builder.WriteString("  ")
+		builder.WriteString(line)
+		builder.WriteString("\n")
+	}
+
+	return builder.String()
+}
+
+// writeSnippet writes the source line holding span with the span underlined,
+// and returns the width of the gutter before the "|". A span that runs onto
+// later lines, like an unterminated string, also shows the line it ends on,
+// which is the line named in the header.
+func (r *Renderer) writeSnippet(builder *strings.Builder, span Span) int {
+	startNumber, startLine, startColumn := r.locate(span.Offset)
+	endNumber, endLine, endColumn := r.locate(span.Offset + span.Length)
+	width := len(fmt.Sprintf("%d", endNumber)) + 1
+
+	builder.WriteString(r.paint(ansiBlue, strings.Repeat(" ", width)+"|"))
+	builder.WriteString("\n")
+	if startNumber == endNumber {
+		r.writeLine(builder, width, startNumber, startLine, startColumn, span.Length)
+		return width
+	}
+
+	r.writeLine(builder, width, startNumber, startLine, startColumn, len(startLine)-startColumn)
+	if endNumber > startNumber+1 {
+		builder.WriteString(r.paint(ansiBlue, "..."))
+		builder.WriteString("\n")
+	}
+	r.writeLine(builder, width, endNumber, endLine, 0, endColumn)
+	return width
+}
+
+// writeLine writes one numbered source line and underlines length bytes of it
+// from column.
+func (r *Renderer) writeLine(builder *strings.Builder, width, lineNumber int, line string, column, length int) {
+	number := fmt.Sprintf("%d", lineNumber)
+	builder.WriteString(r.paint(ansiBlue, number+strings.Repeat(" ", width-len(number))+"|"))
+	if line != "" {
+		builder.WriteString(" ")
+		builder.WriteString(line)
+	}
+	builder.WriteString("\n")
+	builder.WriteString(r.paint(ansiBlue, strings.Repeat(" ", width)+"|"))
+	builder.WriteString(" ")
+	builder.WriteString(padding(line[:column]))
+	builder.WriteString(r.paint(ansiRed, strings.Repeat("^", underlineWidth(line[column:], length))))
+	builder.WriteString("\n")
+}
+
+// locate finds the line containing offset and returns its number, its text
+// and the byte column of offset within it. The number agrees with the line the
+// scanner counted, so an offset at the very end of a source that ends in a
+// newline lands on the empty line after it.
+func (r *Renderer) locate(offset int) (int, string, int) {
+	if offset > len(r.source) {
+		offset = len(r.source)
+	}
+
+	lineStart := strings.LastIndexByte(r.source[:offset], '\n') + 1
+	lineEnd := strings.IndexByte(r.source[offset:], '\n')
+	if lineEnd < 0 {
+		lineEnd = len(r.source)
+	} else {
+		lineEnd += offset
+	}
+
+	lineNumber := strings.Count(r.source[:lineStart], "\n") + 1
+	line := strings.TrimRight(r.source[lineStart:lineEnd], "\r")
+	column := offset - lineStart
+	if column > len(line) {
+		column = len(line)
+	}
+	return lineNumber, line, column
+}
+
+func (r *Renderer) paint(code, text string) string {
+	if !r.color {
+		return text
+	}
+	return code + text + ansiReset
+}
+
+// padding returns whitespace as wide as prefix, keeping its tabs so the caret
+// lines up however the terminal expands them.
+func padding(prefix string) string {
+	var builder strings.Builder
+	for _, c := range prefix {
+		if c == '\t' {
+			builder.WriteRune('\t')
+		} else {
+			builder.WriteRune(' ')
+		}
+	}
+	return builder.String()
+}
+
+// underlineWidth is the number of carets for a span of length bytes starting
+// at the beginning of rest. Spans running past the end of the line are cut
+// there, and every span gets at least one caret.
+func underlineWidth(rest string, length int) int {
+	if length > len(rest) {
+		length = len(rest)
+	}
+	width := utf8.RuneCountInString(rest[:length])
+	if width < 1 {
+		width = 1
+	}
+	return width
+}
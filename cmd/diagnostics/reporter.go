@@ -0,0 +1,34 @@
+package diagnostics
+
+import "io"
+
+// Reporter receives diagnostics as the scanner, parser, resolver and
+// interpreter find them. Those passes never write to the terminal themselves.
+type Reporter interface {
+	Report(diagnostic *Diagnostic)
+}
+
+// WriterReporter renders each diagnostic to a writer, as the CLI does with
+// os.Stderr.
+type WriterReporter struct {
+	writer   io.Writer
+	renderer *Renderer
+}
+
+func NewWriterReporter(writer io.Writer, renderer *Renderer) *WriterReporter {
+	return &WriterReporter{writer: writer, renderer: renderer}
+}
+
+func (w *WriterReporter) Report(diagnostic *Diagnostic) {
+	io.WriteString(w.writer, w.renderer.Render(diagnostic))
+}
+
+// Collector keeps every diagnostic it is given, for embedders and tests that
+// want them as values.
+type Collector struct {
+	Diagnostics Errors
+}
+
+func (c *Collector) Report(diagnostic *Diagnostic) {
+	c.Diagnostics = append(c.Diagnostics, diagnostic)
+}
@@ -0,0 +1,102 @@
+package diagnostics_test
+
+import (
+	"testing"
+
+	"github.com/codecrafters-io/interpreter-starter-go/cmd/diagnostics"
+	"github.com/codecrafters-io/interpreter-starter-go/cmd/interpreter"
+	"github.com/codecrafters-io/interpreter-starter-go/cmd/parser"
+	"github.com/codecrafters-io/interpreter-starter-go/cmd/resolver"
+	"github.com/codecrafters-io/interpreter-starter-go/cmd/scanner"
+)
+
+// collect runs source through every pass, stopping after the first one that
+// fails, and returns what the passes reported.
+func collect(source string) diagnostics.Errors {
+	collector := &diagnostics.Collector{}
+
+	s := scanner.NewScanner(source, collector)
+	tokens := s.ScanTokens()
+	if s.HadError() {
+		return collector.Diagnostics
+	}
+
+	statements, err := parser.NewParser(tokens, collector).ParseStatements()
+	if err != nil {
+		return collector.Diagnostics
+	}
+
+	interp := interpreter.NewInterpreter(collector)
+	if err := resolver.NewResolver(interp, collector).Resolve(statements); err != nil {
+		return collector.Diagnostics
+	}
+
+	interp.Interpret(statements)
+	return collector.Diagnostics
+}
+
+func TestCollectorReceivesDiagnostics(t *testing.T) {
+	tests := []struct {
+		name    string
+		source  string
+		kind    diagnostics.Kind
+		span    diagnostics.Span
+		message string
+	}{
+		{
+			name:    "scanner",
+			source:  `var a = "abc`,
+			kind:    diagnostics.KindStatic,
+			span:    diagnostics.Span{Line: 1, Column: 9, Offset: 8, Length: 4},
+			message: "Unterminated string.",
+		},
+		{
+			name:    "parser",
+			source:  "print 1 +;",
+			kind:    diagnostics.KindStatic,
+			span:    diagnostics.Span{Line: 1, Column: 10, Offset: 9, Length: 1},
+			message: "Expect expression.",
+		},
+		{
+			name:    "resolver",
+			source:  "{ var a = 1; var a = 2; }",
+			kind:    diagnostics.KindStatic,
+			span:    diagnostics.Span{Line: 1, Column: 18, Offset: 17, Length: 1},
+			message: "Already a variable with this name in this scope.",
+		},
+		{
+			name:    "interpreter",
+			source:  `print -"x";`,
+			kind:    diagnostics.KindRuntime,
+			span:    diagnostics.Span{Line: 1, Column: 7, Offset: 6, Length: 1},
+			message: "Operand must be a number.",
+		},
+		{
+			name:    "interpreter on a later line",
+			source:  "\nprint nil + 1;",
+			kind:    diagnostics.KindRuntime,
+			span:    diagnostics.Span{Line: 2, Column: 11, Offset: 11, Length: 1},
+			message: "Operands must be numbers.",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			reported := collect(test.source)
+			if len(reported) != 1 {
+				t.Fatalf("got %d diagnostics, want 1: %v", len(reported), reported)
+			}
+
+			got := reported[0]
+			if got.Kind != test.kind {
+				t.Errorf("Kind = %v, want %v", got.Kind, test.kind)
+			}
+			if got.Span != test.span {
+				t.Errorf("Span = %+v, want %+v", got.Span, test.span)
+			}
+			if got.Message != test.message {
+				t.Errorf("Message = %q, want %q", got.Message, test.message)
+			}
+		})
+	}
+}
@@ -0,0 +1,60 @@
+package interpreter
+
+import (
+	"fmt"
+
+	"github.com/codecrafters-io/interpreter-starter-go/cmd/parser"
+)
+
+// Callable is implemented by every value that can appear as the callee of a
+// call expression. Call returns a *RuntimeError when the body fails; any
+// other error is raised at the call site.
+type Callable interface {
+	Arity() int
+	Call(interpreter *Interpreter, arguments []interface{}) (interface{}, error)
+}
+
+type LoxFunction struct {
+	declaration   *parser.FunctionStmt
+	closure       *Environment
+	isInitializer bool
+}
+
+func NewLoxFunction(declaration *parser.FunctionStmt, closure *Environment, isInitializer bool) *LoxFunction {
+	return &LoxFunction{declaration: declaration, closure: closure, isInitializer: isInitializer}
+}
+
+// Bind returns a copy of the method whose closure defines "this" as instance.
+func (f *LoxFunction) Bind(instance *LoxInstance) *LoxFunction {
+	environment := NewEnvironment(f.closure)
+	environment.Define("this", instance)
+	return NewLoxFunction(f.declaration, environment, f.isInitializer)
+}
+
+func (f *LoxFunction) Arity() int {
+	return len(f.declaration.Params)
+}
+
+func (f *LoxFunction) Call(interpreter *Interpreter, arguments []interface{}) (interface{}, error) {
+	environment := NewEnvironment(f.closure)
+	for idx, param := range f.declaration.Params {
+		environment.Define(param.Lexeme, arguments[idx])
+	}
+
+	err := interpreter.executeBlock(f.declaration.Body, environment)
+	if runtimeErr, ok := err.(*RuntimeError); ok {
+		return nil, runtimeErr
+	}
+	if f.isInitializer {
+		// init always hands back the instance, even on an early return.
+		return f.closure.GetAt(0, "this"), nil
+	}
+	if ret, ok := err.(*returnSignal); ok {
+		return ret.value, nil
+	}
+	return nil, nil
+}
+
+func (f *LoxFunction) String() string {
+	return fmt.Sprintf("<fn %s>", f.declaration.Name.Lexeme)
+}
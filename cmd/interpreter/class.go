@@ -0,0 +1,80 @@
+package interpreter
+
+import (
+	"fmt"
+
+	"github.com/codecrafters-io/interpreter-starter-go/cmd/scanner"
+)
+
+type LoxClass struct {
+	name       string
+	superclass *LoxClass
+	methods    map[string]*LoxFunction
+}
+
+func NewLoxClass(name string, superclass *LoxClass, methods map[string]*LoxFunction) *LoxClass {
+	return &LoxClass{name: name, superclass: superclass, methods: methods}
+}
+
+// FindMethod looks the method up on this class and then along the superclass
+// chain. It returns nil if no class in the chain defines it.
+func (c *LoxClass) FindMethod(name string) *LoxFunction {
+	if method, ok := c.methods[name]; ok {
+		return method
+	}
+	if c.superclass != nil {
+		return c.superclass.FindMethod(name)
+	}
+	return nil
+}
+
+// Arity is the arity of the class's initializer, or zero without one.
+func (c *LoxClass) Arity() int {
+	if initializer := c.FindMethod("init"); initializer != nil {
+		return initializer.Arity()
+	}
+	return 0
+}
+
+func (c *LoxClass) Call(interpreter *Interpreter, arguments []interface{}) (interface{}, error) {
+	instance := NewLoxInstance(c)
+	if initializer := c.FindMethod("init"); initializer != nil {
+		if _, err := initializer.Bind(instance).Call(interpreter, arguments); err != nil {
+			return nil, err
+		}
+	}
+	return instance, nil
+}
+
+func (c *LoxClass) String() string {
+	return c.name
+}
+
+type LoxInstance struct {
+	class  *LoxClass
+	fields map[string]interface{}
+}
+
+func NewLoxInstance(class *LoxClass) *LoxInstance {
+	return &LoxInstance{class: class, fields: make(map[string]interface{})}
+}
+
+// Get looks up a field first and then a method, so fields shadow methods of
+// the same name.
+func (in *LoxInstance) Get(name scanner.Token) (interface{}, error) {
+	if value, ok := in.fields[name.Lexeme]; ok {
+		return value, nil
+	}
+	if method := in.class.FindMethod(name.Lexeme); method != nil {
+		return method.Bind(in), nil
+	}
+	return nil, fmt.Errorf("Undefined property '%s'.", name.Lexeme)
+}
+
+func (in *LoxInstance) Set(name scanner.Token, value interface{}) {
+	in.fields[name.Lexeme] = value
+}
+
+func (in *LoxInstance) String() string {
+	return in.class.name + " instance"
+}
@@ -42,3 +42,21 @@ func (e *Environment) Assign(name scanner.Token, value interface{}) error {
 	}
 	return fmt.Errorf("Undefined variable '%s'.", name.Lexeme)
 }
+
+// GetAt reads a variable the resolver found exactly distance scopes up.
+func (e *Environment) GetAt(distance int, name string) interface{} {
+	return e.ancestor(distance).values[name]
+}
+
+// AssignAt writes a variable the resolver found exactly distance scopes up.
+func (e *Environment) AssignAt(distance int, name scanner.Token, value interface{}) {
+	e.ancestor(distance).values[name.Lexeme] = value
+}
+
+func (e *Environment) ancestor(distance int) *Environment {
+	environment := e
+	for i := 0; i < distance; i++ {
+		environment = environment.enclosing
+	}
+	return environment
+}
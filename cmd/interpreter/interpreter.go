@@ -2,9 +2,11 @@ package interpreter
 
 import (
 	"fmt"
-	"os"
+	"math"
 	"strconv"
+	"strings"
 
+	"github.com/codecrafters-io/interpreter-starter-go/cmd/diagnostics"
 	"github.com/codecrafters-io/interpreter-starter-go/cmd/parser"
 	"github.com/codecrafters-io/interpreter-starter-go/cmd/scanner"
 )
@@ -12,41 +14,180 @@ import (
 type RuntimeError struct {
 	Token   scanner.Token
 	Message string
+	// Help is an optional hint shown under the source snippet.
+	Help string
+	// Trace is the call stack when the error happened, outermost call first.
+	// It is empty for errors raised by top-level code.
+	Trace []CallFrame
 }
 
 func (e *RuntimeError) Error() string {
-	return fmt.Sprintf("[line %d]%s\n", e.Token.Line, e.Message)
+	return e.Diagnostic().Error()
+}
+
+// CallFrame is one active call of a Lox function or class.
+type CallFrame struct {
+	Function string
+	// Call is the closing parenthesis of the call expression.
+	Call scanner.Token
+}
+
+// maxTraceLines caps a traceback; deep recursion keeps only the innermost and
+// outermost calls.
+const maxTraceLines = 20
+
+// Traceback describes Trace innermost call first, giving for each function
+// the line it had reached, as in "at fib (line 4)". Top-level code is shown
+// as <script>.
+func (e *RuntimeError) Traceback() []string {
+	if len(e.Trace) == 0 {
+		return nil
+	}
+
+	lines := make([]string, 0, len(e.Trace)+1)
+	line := e.Token.Line
+	for idx := len(e.Trace) - 1; idx >= 0; idx-- {
+		lines = append(lines, fmt.Sprintf("at %s (line %d)", e.Trace[idx].Function, line))
+		line = e.Trace[idx].Call.Line
+	}
+	lines = append(lines, fmt.Sprintf("at <script> (line %d)", line))
+
+	if len(lines) > maxTraceLines {
+		kept := maxTraceLines / 2
+		elided := fmt.Sprintf("... %d more calls ...", len(lines)-2*kept)
+		lines = append(append(lines[:kept:kept], elided), lines[len(lines)-kept:]...)
+	}
+	return lines
+}
+
+// Diagnostic describes the error for a diagnostics.Reporter.
+func (e *RuntimeError) Diagnostic() *diagnostics.Diagnostic {
+	return &diagnostics.Diagnostic{
+		Kind:    diagnostics.KindRuntime,
+		Span:    e.Token.Span(),
+		Message: e.Message,
+		Help:    e.Help,
+		Trace:   e.Traceback(),
+	}
+}
+
+// breakSignal and continueSignal unwind execution out of a loop body. Like
+// fs.SkipDir they travel through the error results of execute rather than
+// being real failures.
+type breakSignal struct {
+	keyword scanner.Token
+	label   string
+}
+
+func (b *breakSignal) Error() string {
+	return "break outside of a loop"
+}
+
+type continueSignal struct {
+	keyword scanner.Token
+	label   string
+}
+
+func (c *continueSignal) Error() string {
+	return "continue outside of a loop"
+}
+
+// returnSignal carries a return value out of a function body.
+type returnSignal struct {
+	keyword scanner.Token
+	value   interface{}
+}
+
+func (r *returnSignal) Error() string {
+	return "return outside of a function"
 }
 
 type Interpreter struct {
-	environment     *Environment
+	globals     *Environment
+	environment *Environment
+	// locals maps each resolved Variable and Assign expression to the number
+	// of scopes between its use and its declaration. Expressions missing from
+	// the map are globals.
+	locals map[parser.Expr]int
+	// frames is the stack of active Lox calls, innermost last.
+	frames []CallFrame
+	// reporter receives the runtime error that stops Evaluate or Interpret.
+	reporter        diagnostics.Reporter
 	HadRuntimeError bool
 }
 
-func NewInterpreter() *Interpreter {
-	return &Interpreter{environment: NewEnvironment(nil)}
+func NewInterpreter(reporter diagnostics.Reporter) *Interpreter {
+	globals := NewEnvironment(nil)
+	interpreter := &Interpreter{
+		globals:     globals,
+		environment: globals,
+		locals:      make(map[parser.Expr]int),
+		reporter:    reporter,
+	}
+	interpreter.registerStandardNatives()
+	return interpreter
 }
 
+// Resolve records the scope depth of a local variable reference. It is called
+// by the resolver before the program runs.
+func (i *Interpreter) Resolve(expr parser.Expr, depth int) {
+	i.locals[expr] = depth
+}
+
+// Evaluate computes the value of expr. Like Interpret, it sends a runtime
+// error to the reporter and also returns it, always as a *RuntimeError.
 func (i *Interpreter) Evaluate(expr parser.Expr) (interface{}, error) {
-	return expr.Accept(i), nil
-}
-
-func (i *Interpreter) Interpret(statements []parser.Stmt) {
-	defer func() {
-		if r := recover(); r != nil {
-			if runtimeErr, ok := r.(*RuntimeError); ok {
-				fmt.Fprintln(os.Stderr, runtimeErr.Error())
-				i.HadRuntimeError = true
-			} else {
-				fmt.Fprintf(os.Stderr, "Unexpected error: %v\n", r)
-				i.HadRuntimeError = true
-			}
-		}
-	}()
+	value, err := i.evaluate(expr)
+	if err != nil {
+		runtimeErr := strayError(err)
+		i.reporter.Report(runtimeErr.Diagnostic())
+		i.HadRuntimeError = true
+		return nil, runtimeErr
+	}
+	return value, nil
+}
+
+// evaluate computes the value of expr without reporting a failure, which is
+// left to whichever entry point the evaluation started from.
+func (i *Interpreter) evaluate(expr parser.Expr) (interface{}, error) {
+	result := expr.Accept(i)
+	if err, ok := result.(error); ok {
+		return nil, err
+	}
+	return result, nil
+}
 
+// Interpret runs the program until it finishes or hits a runtime error. Like
+// Evaluate, it sends the error to the reporter and also returns it, always as
+// a *RuntimeError.
+func (i *Interpreter) Interpret(statements []parser.Stmt) error {
 	for _, stmt := range statements {
-		i.execute(stmt)
+		if err := i.execute(stmt); err != nil {
+			runtimeErr := strayError(err)
+			i.reporter.Report(runtimeErr.Diagnostic())
+			i.HadRuntimeError = true
+			return runtimeErr
+		}
+	}
+	return nil
+}
+
+// strayError turns whatever unwound out of a statement into a runtime error.
+// The parser and resolver keep break, continue and return inside loops and
+// functions, but a program that skipped the resolver can still reach the top
+// level with a return.
+func strayError(err error) *RuntimeError {
+	switch signal := err.(type) {
+	case *RuntimeError:
+		return signal
+	case *breakSignal:
+		return &RuntimeError{Token: signal.keyword, Message: "Can't use 'break' outside of a loop."}
+	case *continueSignal:
+		return &RuntimeError{Token: signal.keyword, Message: "Can't use 'continue' outside of a loop."}
+	case *returnSignal:
+		return &RuntimeError{Token: signal.keyword, Message: "Can't return from top-level code."}
 	}
+	return &RuntimeError{Message: err.Error()}
 }
 
 func (i *Interpreter) execute(stmt parser.Stmt) error {
@@ -57,54 +198,172 @@ func (i *Interpreter) execute(stmt parser.Stmt) error {
 	return nil
 }
 
+// valueOrError folds the results of a helper into the single value a visitor
+// returns. Visitors hand back a *RuntimeError in place of a value, the same
+// way statements hand back control-flow signals.
+func valueOrError(value interface{}, err error) interface{} {
+	if err != nil {
+		return err
+	}
+	return value
+}
+
 func (i *Interpreter) VisitPrintStmt(stmt *parser.PrintStmt) interface{} {
-	value, err := i.Evaluate(stmt.Expression)
+	value, err := i.evaluate(stmt.Expression)
 	if err != nil {
-		panic(err)
+		return err
 	}
-	fmt.Println(i.stringify(value))
+	fmt.Println(i.Stringify(value))
 	return nil
 }
 
 func (i *Interpreter) VisitExpressionStmt(stmt *parser.ExpressionStmt) interface{} {
-	_, err := i.Evaluate(stmt.Expression)
-	if err != nil {
-		panic(err)
+	if _, err := i.evaluate(stmt.Expression); err != nil {
+		return err
 	}
 	return nil
 }
 
 func (i *Interpreter) VisitBlockStmt(stmt *parser.BlockStmt) interface{} {
 	newEnv := NewEnvironment(i.environment)
-	i.executeBlock(stmt.Statements, newEnv)
+	return i.executeBlock(stmt.Statements, newEnv)
+}
+
+func (i *Interpreter) VisitIfStmt(stmt *parser.IfStmt) interface{} {
+	condition, err := i.evaluate(stmt.Condition)
+	if err != nil {
+		return err
+	}
+
+	if i.isTruthy(condition) {
+		return i.execute(stmt.ThenBranch)
+	} else if stmt.ElseBranch != nil {
+		return i.execute(stmt.ElseBranch)
+	}
 	return nil
 }
 
-func (i *Interpreter) executeBlock(statements []parser.Stmt, environment *Environment) {
-	previous := i.environment
-	defer func() { i.environment = previous }()
+func (i *Interpreter) VisitWhileStmt(stmt *parser.WhileStmt) interface{} {
+	label := ""
+	if stmt.Label != nil {
+		label = stmt.Label.Lexeme
+	}
 
-	i.environment = environment
+	for {
+		condition, err := i.evaluate(stmt.Condition)
+		if err != nil {
+			return err
+		}
+		if !i.isTruthy(condition) {
+			return nil
+		}
 
-	for _, stmt := range statements {
-		i.execute(stmt)
+		switch signal := i.execute(stmt.Body).(type) {
+		case nil:
+		case *breakSignal:
+			if signal.label != "" && signal.label != label {
+				return signal
+			}
+			return nil
+		case *continueSignal:
+			if signal.label != "" && signal.label != label {
+				return signal
+			}
+		default:
+			return signal
+		}
+
+		if stmt.Increment != nil {
+			if _, err := i.evaluate(stmt.Increment); err != nil {
+				return err
+			}
+		}
 	}
 }
 
-func (i *Interpreter) stringify(value interface{}) string {
-	if value == nil {
-		return "nil"
+func (i *Interpreter) VisitBreakStmt(stmt *parser.BreakStmt) interface{} {
+	if stmt.Label != nil {
+		return &breakSignal{keyword: stmt.Keyword, label: stmt.Label.Lexeme}
 	}
-	if b, ok := value.(bool); ok {
-		return strconv.FormatBool(b)
+	return &breakSignal{keyword: stmt.Keyword}
+}
+
+func (i *Interpreter) VisitContinueStmt(stmt *parser.ContinueStmt) interface{} {
+	if stmt.Label != nil {
+		return &continueSignal{keyword: stmt.Keyword, label: stmt.Label.Lexeme}
 	}
-	if n, ok := value.(float64); ok {
-		return strconv.FormatFloat(n, 'f', -1, 64)
+	return &continueSignal{keyword: stmt.Keyword}
+}
+
+func (i *Interpreter) VisitFunctionStmt(stmt *parser.FunctionStmt) interface{} {
+	function := NewLoxFunction(stmt, i.environment, false)
+	i.environment.Define(stmt.Name.Lexeme, function)
+	return nil
+}
+
+func (i *Interpreter) VisitClassStmt(stmt *parser.ClassStmt) interface{} {
+	var superclass *LoxClass
+	if stmt.Superclass != nil {
+		value, err := i.evaluate(stmt.Superclass)
+		if err != nil {
+			return err
+		}
+		var ok bool
+		superclass, ok = value.(*LoxClass)
+		if !ok {
+			return &RuntimeError{Token: stmt.Superclass.Name, Message: "Superclass must be a class."}
+		}
 	}
-	if s, ok := value.(string); ok {
-		return s
+
+	i.environment.Define(stmt.Name.Lexeme, nil)
+
+	// Methods of a subclass close over an extra scope that binds "super".
+	if superclass != nil {
+		i.environment = NewEnvironment(i.environment)
+		i.environment.Define("super", superclass)
 	}
-	return fmt.Sprintf("%v", value)
+
+	methods := make(map[string]*LoxFunction)
+	for _, method := range stmt.Methods {
+		methods[method.Name.Lexeme] = NewLoxFunction(method, i.environment, method.Name.Lexeme == "init")
+	}
+
+	class := NewLoxClass(stmt.Name.Lexeme, superclass, methods)
+
+	if superclass != nil {
+		i.environment = i.environment.enclosing
+	}
+
+	if err := i.environment.Assign(stmt.Name, class); err != nil {
+		return &RuntimeError{Token: stmt.Name, Message: err.Error()}
+	}
+	return nil
+}
+
+func (i *Interpreter) VisitReturnStmt(stmt *parser.ReturnStmt) interface{} {
+	var value interface{}
+	if stmt.Value != nil {
+		var err error
+		value, err = i.evaluate(stmt.Value)
+		if err != nil {
+			return err
+		}
+	}
+	return &returnSignal{keyword: stmt.Keyword, value: value}
+}
+
+func (i *Interpreter) executeBlock(statements []parser.Stmt, environment *Environment) error {
+	previous := i.environment
+	defer func() { i.environment = previous }()
+
+	i.environment = environment
+
+	for _, stmt := range statements {
+		if err := i.execute(stmt); err != nil {
+			return err
+		}
+	}
+	return nil
 }
 
 func (i *Interpreter) VisitLiteralExpr(expr *parser.Literal) interface{} {
@@ -112,19 +371,21 @@ func (i *Interpreter) VisitLiteralExpr(expr *parser.Literal) interface{} {
 }
 
 func (i *Interpreter) VisitGroupingExpr(expr *parser.Grouping) interface{} {
-	eval, _ := i.Evaluate(expr.Expression)
-	return eval
+	return valueOrError(i.evaluate(expr.Expression))
 }
 
 func (i *Interpreter) VisitUnaryExpr(expr *parser.Unary) interface{} {
-	right := expr.Right.Accept(i)
+	right, err := i.evaluate(expr.Right)
+	if err != nil {
+		return err
+	}
 
 	switch expr.Operator.Type {
 	case scanner.MINUS:
 		if num, ok := right.(float64); ok {
 			return -num
 		}
-		panic(RuntimeError{Token: expr.Operator, Message: "Operand must be a number."})
+		return &RuntimeError{Token: expr.Operator, Message: "Operand must be a number."}
 	case scanner.BANG:
 		return !i.isTruthy(right)
 	}
@@ -134,102 +395,564 @@ func (i *Interpreter) VisitUnaryExpr(expr *parser.Unary) interface{} {
 }
 
 func (i *Interpreter) VisitBinaryExpr(expr *parser.Binary) interface{} {
-	left := expr.Left.Accept(i)
-	right := expr.Right.Accept(i)
+	left, err := i.evaluate(expr.Left)
+	if err != nil {
+		return err
+	}
+	right, err := i.evaluate(expr.Right)
+	if err != nil {
+		return err
+	}
+	return valueOrError(i.applyBinary(expr.Operator, left, right))
+}
 
-	switch expr.Operator.Type {
+func (i *Interpreter) applyBinary(operator scanner.Token, left, right interface{}) (interface{}, error) {
+	switch operator.Type {
 	case scanner.MINUS:
-		return i.checkNumberOperands(expr.Operator, left, right)
+		return i.checkNumberOperands(operator, left, right)
 	case scanner.PLUS:
 		if leftStr, leftOk := left.(string); leftOk {
 			if rightStr, rightOk := right.(string); rightOk {
-				return leftStr + rightStr
+				return leftStr + rightStr, nil
 			}
 		}
-		return i.checkNumberOperands(expr.Operator, left, right)
+		return i.checkNumberOperands(operator, left, right)
 	case scanner.STAR:
-		return i.checkNumberOperands(expr.Operator, left, right)
+		return i.checkNumberOperands(operator, left, right)
 	case scanner.SLASH:
-		return i.checkNumberOperands(expr.Operator, left, right)
+		return i.checkNumberOperands(operator, left, right)
+	case scanner.PERCENT:
+		return i.checkNumberOperands(operator, left, right)
+	case scanner.TILDE_SLASH:
+		return i.checkNumberOperands(operator, left, right)
+	case scanner.STAR_STAR:
+		return i.checkNumberOperands(operator, left, right)
 	case scanner.GREATER:
-		return i.checkNumberOperands(expr.Operator, left, right)
+		return i.checkNumberOperands(operator, left, right)
 	case scanner.GREATER_EQUAL:
-		return i.checkNumberOperands(expr.Operator, left, right)
+		return i.checkNumberOperands(operator, left, right)
 	case scanner.LESS:
-		return i.checkNumberOperands(expr.Operator, left, right)
+		return i.checkNumberOperands(operator, left, right)
 	case scanner.LESS_EQUAL:
-		return i.checkNumberOperands(expr.Operator, left, right)
+		return i.checkNumberOperands(operator, left, right)
+	case scanner.COMMA:
+		// Both operands have been evaluated for their effects; the sequence
+		// yields the right one.
+		return right, nil
 	case scanner.EQUAL_EQUAL:
-		return i.isEqual(left, right)
+		return i.isEqual(left, right), nil
 	case scanner.BANG_EQUAL:
-		return !i.isEqual(left, right)
+		return !i.isEqual(left, right), nil
 	}
 
 	// Unreachable
-	return nil
+	return nil, nil
+}
+
+func (i *Interpreter) VisitConditionalExpr(expr *parser.Conditional) interface{} {
+	condition, err := i.evaluate(expr.Condition)
+	if err != nil {
+		return err
+	}
+
+	// Only the branch that is taken gets evaluated.
+	if i.isTruthy(condition) {
+		return valueOrError(i.evaluate(expr.ThenBranch))
+	}
+	return valueOrError(i.evaluate(expr.ElseBranch))
+}
+
+func (i *Interpreter) VisitLogicalExpr(expr *parser.Logical) interface{} {
+	left, err := i.evaluate(expr.Left)
+	if err != nil {
+		return err
+	}
+
+	// Short-circuit and hand back the operand itself rather than a bool.
+	if expr.Operator.Type == scanner.OR {
+		if i.isTruthy(left) {
+			return left
+		}
+	} else {
+		if !i.isTruthy(left) {
+			return left
+		}
+	}
+
+	return valueOrError(i.evaluate(expr.Right))
+}
+
+func (i *Interpreter) VisitCallExpr(expr *parser.Call) interface{} {
+	callee, err := i.evaluate(expr.Callee)
+	if err != nil {
+		return err
+	}
+
+	arguments := make([]interface{}, 0, len(expr.Arguments))
+	for _, argument := range expr.Arguments {
+		value, err := i.evaluate(argument)
+		if err != nil {
+			return err
+		}
+		arguments = append(arguments, value)
+	}
+
+	function, ok := callee.(Callable)
+	if !ok {
+		return &RuntimeError{Token: expr.Paren, Message: "Can only call functions and classes."}
+	}
+	if len(arguments) != function.Arity() {
+		return &RuntimeError{
+			Token:   expr.Paren,
+			Message: fmt.Sprintf("Expected %d arguments but got %d.", function.Arity(), len(arguments)),
+		}
+	}
+
+	value, err := i.call(function, expr.Paren, arguments)
+	if err != nil {
+		// Errors from natives carry no location until they get one here.
+		if runtimeErr, ok := err.(*RuntimeError); ok {
+			return runtimeErr
+		}
+		return &RuntimeError{Token: expr.Paren, Message: err.Error()}
+	}
+	return value
+}
+
+// maxCallDepth bounds recursion so that runaway programs fail with a runtime
+// error rather than exhausting the Go stack.
+const maxCallDepth = 10000
+
+// call invokes function inside a new call frame. The innermost frame a runtime
+// error unwinds through records the whole stack on it.
+func (i *Interpreter) call(function Callable, paren scanner.Token, arguments []interface{}) (interface{}, error) {
+	var name string
+	switch callee := function.(type) {
+	case *LoxFunction:
+		name = callee.declaration.Name.Lexeme
+	case *LoxClass:
+		name = callee.name
+	default:
+		// Natives fail at the call site, so they get no frame of their own.
+		return function.Call(i, arguments)
+	}
+
+	if len(i.frames) >= maxCallDepth {
+		return nil, &RuntimeError{Token: paren, Message: "Stack overflow."}
+	}
+	i.frames = append(i.frames, CallFrame{Function: name, Call: paren})
+	defer func() { i.frames = i.frames[:len(i.frames)-1] }()
+
+	value, err := function.Call(i, arguments)
+	if runtimeErr, ok := err.(*RuntimeError); ok && runtimeErr.Trace == nil {
+		runtimeErr.Trace = append([]CallFrame(nil), i.frames...)
+	}
+	return value, err
+}
+
+func (i *Interpreter) VisitListExpr(expr *parser.List) interface{} {
+	elements := make([]interface{}, 0, len(expr.Elements))
+	for _, element := range expr.Elements {
+		value, err := i.evaluate(element)
+		if err != nil {
+			return err
+		}
+		elements = append(elements, value)
+	}
+	return NewLoxList(elements)
+}
+
+func (i *Interpreter) VisitMapExpr(expr *parser.Map) interface{} {
+	m := NewLoxMap()
+	for idx, keyExpr := range expr.Keys {
+		key, err := i.evaluate(keyExpr)
+		if err != nil {
+			return err
+		}
+		if err := checkMapKey(key); err != nil {
+			return &RuntimeError{Token: expr.Brace, Message: err.Error()}
+		}
+		value, err := i.evaluate(expr.Values[idx])
+		if err != nil {
+			return err
+		}
+		m.Set(key, value)
+	}
+	return m
+}
+
+func (i *Interpreter) VisitInterpolationExpr(expr *parser.Interpolation) interface{} {
+	var builder strings.Builder
+	for _, part := range expr.Parts {
+		value, err := i.evaluate(part)
+		if err != nil {
+			return err
+		}
+		builder.WriteString(i.Stringify(value))
+	}
+	return builder.String()
+}
+
+func (i *Interpreter) VisitIndexExpr(expr *parser.Index) interface{} {
+	object, err := i.evaluate(expr.Object)
+	if err != nil {
+		return err
+	}
+	index, err := i.evaluate(expr.Index)
+	if err != nil {
+		return err
+	}
+	return valueOrError(i.indexGet(expr.Bracket, object, index))
+}
+
+func (i *Interpreter) VisitIndexSetExpr(expr *parser.IndexSet) interface{} {
+	object, err := i.evaluate(expr.Object)
+	if err != nil {
+		return err
+	}
+	index, err := i.evaluate(expr.Index)
+	if err != nil {
+		return err
+	}
+	value, err := i.evaluate(expr.Value)
+	if err != nil {
+		return err
+	}
+	if err := i.indexSet(expr.Bracket, object, index, value); err != nil {
+		return err
+	}
+	return value
+}
+
+func (i *Interpreter) indexGet(bracket scanner.Token, object, index interface{}) (interface{}, error) {
+	switch collection := object.(type) {
+	case *LoxList:
+		value, err := collection.Get(index)
+		if err != nil {
+			return nil, &RuntimeError{Token: bracket, Message: err.Error()}
+		}
+		return value, nil
+	case *LoxMap:
+		if err := checkMapKey(index); err != nil {
+			return nil, &RuntimeError{Token: bracket, Message: err.Error()}
+		}
+		value, ok := collection.Get(index)
+		if !ok {
+			return nil, &RuntimeError{
+				Token:   bracket,
+				Message: fmt.Sprintf("Undefined key %s.", i.stringifyElement(index, nil)),
+			}
+		}
+		return value, nil
+	}
+	return nil, &RuntimeError{Token: bracket, Message: "Only lists and maps can be indexed."}
+}
+
+func (i *Interpreter) indexSet(bracket scanner.Token, object, index, value interface{}) error {
+	switch collection := object.(type) {
+	case *LoxList:
+		if err := collection.Set(index, value); err != nil {
+			return &RuntimeError{Token: bracket, Message: err.Error()}
+		}
+		return nil
+	case *LoxMap:
+		if err := checkMapKey(index); err != nil {
+			return &RuntimeError{Token: bracket, Message: err.Error()}
+		}
+		collection.Set(index, value)
+		return nil
+	}
+	return &RuntimeError{Token: bracket, Message: "Only lists and maps can be indexed."}
+}
+
+func (i *Interpreter) VisitGetExpr(expr *parser.Get) interface{} {
+	object, err := i.evaluate(expr.Object)
+	if err != nil {
+		return err
+	}
+	instance, ok := object.(*LoxInstance)
+	if !ok {
+		return &RuntimeError{Token: expr.Name, Message: "Only instances have properties."}
+	}
+
+	value, err := instance.Get(expr.Name)
+	if err != nil {
+		return &RuntimeError{Token: expr.Name, Message: err.Error()}
+	}
+	return value
+}
+
+func (i *Interpreter) VisitSetExpr(expr *parser.Set) interface{} {
+	object, err := i.evaluate(expr.Object)
+	if err != nil {
+		return err
+	}
+	instance, ok := object.(*LoxInstance)
+	if !ok {
+		return &RuntimeError{Token: expr.Name, Message: "Only instances have fields."}
+	}
+
+	value, err := i.evaluate(expr.Value)
+	if err != nil {
+		return err
+	}
+	instance.Set(expr.Name, value)
+	return value
+}
+
+func (i *Interpreter) VisitSuperExpr(expr *parser.Super) interface{} {
+	// The resolver rejects 'super' outside a subclass, but an expression
+	// evaluated without resolving it can still get here.
+	distance, ok := i.locals[expr]
+	if !ok {
+		return &RuntimeError{Token: expr.Keyword, Message: "Can't use 'super' outside of a class."}
+	}
+	superclass, ok := i.environment.GetAt(distance, "super").(*LoxClass)
+	if !ok {
+		return &RuntimeError{Token: expr.Keyword, Message: "Can't use 'super' outside of a class."}
+	}
+
+	// "this" is always bound one scope inside the one holding "super".
+	object, ok := i.environment.GetAt(distance-1, "this").(*LoxInstance)
+	if !ok {
+		return &RuntimeError{Token: expr.Keyword, Message: "Can't use 'super' outside of a method."}
+	}
+
+	method := superclass.FindMethod(expr.Method.Lexeme)
+	if method == nil {
+		return &RuntimeError{
+			Token:   expr.Method,
+			Message: fmt.Sprintf("Undefined property '%s'.", expr.Method.Lexeme),
+		}
+	}
+	return method.Bind(object)
+}
+
+func (i *Interpreter) VisitThisExpr(expr *parser.This) interface{} {
+	return valueOrError(i.lookUpVariable(expr.Keyword, expr))
 }
 
 func (i *Interpreter) VisitVariableExpr(expr *parser.Variable) interface{} {
-	value, err := i.environment.Get(expr.Name)
+	return valueOrError(i.lookUpVariable(expr.Name, expr))
+}
+
+func (i *Interpreter) lookUpVariable(name scanner.Token, expr parser.Expr) (interface{}, error) {
+	if distance, ok := i.locals[expr]; ok {
+		return i.environment.GetAt(distance, name.Lexeme), nil
+	}
+
+	value, err := i.globals.Get(name)
 	if err != nil {
-		panic(&RuntimeError{
-			Token:   expr.Name,
+		return nil, &RuntimeError{
+			Token:   name,
 			Message: err.Error(),
-		})
+			Help:    "Declare it with 'var' before using it.",
+		}
 	}
-	return value
+	return value, nil
 }
 
 func (i *Interpreter) VisitAssignExpr(expr *parser.Assign) interface{} {
-	value := expr.Value.Accept(i)
+	value, err := i.evaluate(expr.Value)
+	if err != nil {
+		return err
+	}
+	if err := i.assignVariable(expr.Name, expr, value); err != nil {
+		return err
+	}
+	return value
+}
 
-	err := i.environment.Assign(expr.Name, value)
+func (i *Interpreter) assignVariable(name scanner.Token, expr parser.Expr, value interface{}) error {
+	if distance, ok := i.locals[expr]; ok {
+		i.environment.AssignAt(distance, name, value)
+		return nil
+	}
+
+	err := i.globals.Assign(name, value)
 	if err != nil {
-		panic(err)
+		return &RuntimeError{
+			Token:   name,
+			Message: err.Error(),
+			Help:    "Declare it with 'var' before assigning to it.",
+		}
+	}
+	return nil
+}
+
+// compoundOperators maps each compound assignment operator to the binary
+// operator it applies.
+var compoundOperators = map[scanner.TokenType]scanner.TokenType{
+	scanner.PLUS_EQUAL:  scanner.PLUS,
+	scanner.MINUS_EQUAL: scanner.MINUS,
+	scanner.STAR_EQUAL:  scanner.STAR,
+	scanner.SLASH_EQUAL: scanner.SLASH,
+}
+
+func (i *Interpreter) VisitCompoundAssignExpr(expr *parser.CompoundAssign) interface{} {
+	get, set, err := i.target(expr.Target, expr.Operator)
+	if err != nil {
+		return err
+	}
+
+	operator := expr.Operator
+	operator.Type = compoundOperators[expr.Operator.Type]
+
+	current, err := get()
+	if err != nil {
+		return err
+	}
+	operand, err := i.evaluate(expr.Value)
+	if err != nil {
+		return err
+	}
+	value, err := i.applyBinary(operator, current, operand)
+	if err != nil {
+		return err
+	}
+	if err := set(value); err != nil {
+		return err
 	}
 	return value
 }
 
+func (i *Interpreter) VisitIncrementExpr(expr *parser.Increment) interface{} {
+	get, set, err := i.target(expr.Target, expr.Operator)
+	if err != nil {
+		return err
+	}
+
+	current, err := get()
+	if err != nil {
+		return err
+	}
+	old, ok := current.(float64)
+	if !ok {
+		return &RuntimeError{Token: expr.Operator, Message: "Operand must be a number."}
+	}
+
+	updated := old + 1
+	if expr.Operator.Type == scanner.MINUS_MINUS {
+		updated = old - 1
+	}
+	if err := set(updated); err != nil {
+		return err
+	}
+
+	if expr.Prefix {
+		return updated
+	}
+	return old
+}
+
+// target evaluates the object and index of an assignable expression exactly
+// once and returns functions that read and write the location it denotes.
+// operator locates the error for any other expression.
+func (i *Interpreter) target(expr parser.Expr, operator scanner.Token) (get func() (interface{}, error), set func(interface{}) error, err error) {
+	switch target := expr.(type) {
+	case *parser.Variable:
+		get = func() (interface{}, error) { return i.lookUpVariable(target.Name, target) }
+		set = func(value interface{}) error { return i.assignVariable(target.Name, target, value) }
+	case *parser.Get:
+		object, err := i.evaluate(target.Object)
+		if err != nil {
+			return nil, nil, err
+		}
+		instance, ok := object.(*LoxInstance)
+		if !ok {
+			return nil, nil, &RuntimeError{Token: target.Name, Message: "Only instances have fields."}
+		}
+		get = func() (interface{}, error) {
+			value, err := instance.Get(target.Name)
+			if err != nil {
+				return nil, &RuntimeError{Token: target.Name, Message: err.Error()}
+			}
+			return value, nil
+		}
+		set = func(value interface{}) error {
+			instance.Set(target.Name, value)
+			return nil
+		}
+	case *parser.Index:
+		object, err := i.evaluate(target.Object)
+		if err != nil {
+			return nil, nil, err
+		}
+		index, err := i.evaluate(target.Index)
+		if err != nil {
+			return nil, nil, err
+		}
+		get = func() (interface{}, error) { return i.indexGet(target.Bracket, object, index) }
+		set = func(value interface{}) error { return i.indexSet(target.Bracket, object, index, value) }
+	default:
+		// The parser only builds compound assignments on the targets above.
+		return nil, nil, &RuntimeError{Token: operator, Message: "Invalid assignment target."}
+	}
+	return get, set, nil
+}
+
 func (i *Interpreter) VisitVarStmt(stmt *parser.VarStmt) interface{} {
 	var value interface{}
 	if stmt.Initializer != nil {
-		value, _ = i.Evaluate(stmt.Initializer)
+		var err error
+		value, err = i.evaluate(stmt.Initializer)
+		if err != nil {
+			return err
+		}
 	}
 	i.environment.Define(stmt.Name.Lexeme, value)
 	return nil
 }
 
-func (i *Interpreter) checkNumberOperands(operator scanner.Token, left, right interface{}) interface{} {
+func (i *Interpreter) checkNumberOperands(operator scanner.Token, left, right interface{}) (interface{}, error) {
 	leftNum, leftOk := left.(float64)
 	rightNum, rightOk := right.(float64)
 	if !leftOk || !rightOk {
-		panic(RuntimeError{Token: operator, Message: "Operands must be numbers."})
+		return nil, &RuntimeError{Token: operator, Message: "Operands must be numbers."}
 	}
 
 	switch operator.Type {
 	case scanner.MINUS:
-		return leftNum - rightNum
+		return leftNum - rightNum, nil
 	case scanner.PLUS:
-		return leftNum + rightNum
+		return leftNum + rightNum, nil
 	case scanner.STAR:
-		return leftNum * rightNum
+		return leftNum * rightNum, nil
 	case scanner.SLASH:
 		if rightNum == 0 {
-			panic(RuntimeError{Token: operator, Message: "Division by zero."})
+			return nil, &RuntimeError{Token: operator, Message: "Division by zero."}
+		}
+		return leftNum / rightNum, nil
+	case scanner.PERCENT:
+		if rightNum == 0 {
+			return nil, &RuntimeError{Token: operator, Message: "Division by zero."}
 		}
-		return leftNum / rightNum
+		// The result takes the sign of the divisor, so that
+		// a == (a ~/ b) * b + a % b holds for floor division.
+		remainder := math.Mod(leftNum, rightNum)
+		if remainder != 0 && (remainder < 0) != (rightNum < 0) {
+			remainder += rightNum
+		}
+		return remainder, nil
+	case scanner.TILDE_SLASH:
+		if rightNum == 0 {
+			return nil, &RuntimeError{Token: operator, Message: "Division by zero."}
+		}
+		return math.Floor(leftNum / rightNum), nil
+	case scanner.STAR_STAR:
+		return math.Pow(leftNum, rightNum), nil
 	case scanner.GREATER:
-		return leftNum > rightNum
+		return leftNum > rightNum, nil
 	case scanner.GREATER_EQUAL:
-		return leftNum >= rightNum
+		return leftNum >= rightNum, nil
 	case scanner.LESS:
-		return leftNum < rightNum
+		return leftNum < rightNum, nil
 	case scanner.LESS_EQUAL:
-		return leftNum <= rightNum
+		return leftNum <= rightNum, nil
 	}
 
 	// Unreachable
-	return nil
+	return nil, nil
 }
 
 func (i *Interpreter) isEqual(a, b interface{}) bool {
@@ -244,6 +967,13 @@ func (i *Interpreter) isEqual(a, b interface{}) bool {
 }
 
 func (i *Interpreter) Stringify(object interface{}) string {
+	return i.stringify(object, nil)
+}
+
+// stringify formats object. printing holds the collections whose contents are
+// being formatted further up, so a list or map that contains itself prints the
+// inner reference as [...] or {...} instead of recursing forever.
+func (i *Interpreter) stringify(object interface{}, printing map[interface{}]bool) string {
 	if object == nil {
 		return "nil"
 	}
@@ -259,9 +989,52 @@ func (i *Interpreter) Stringify(object interface{}) string {
 	if str, ok := object.(string); ok {
 		return str
 	}
+	if list, ok := object.(*LoxList); ok {
+		if printing[list] {
+			return "[...]"
+		}
+		if printing == nil {
+			printing = make(map[interface{}]bool)
+		}
+		printing[list] = true
+		defer delete(printing, list)
+
+		elements := make([]string, len(list.elements))
+		for idx, element := range list.elements {
+			elements[idx] = i.stringifyElement(element, printing)
+		}
+		return "[" + strings.Join(elements, ", ") + "]"
+	}
+	if m, ok := object.(*LoxMap); ok {
+		if printing[m] {
+			return "{...}"
+		}
+		if printing == nil {
+			printing = make(map[interface{}]bool)
+		}
+		printing[m] = true
+		defer delete(printing, m)
+
+		keys := m.Keys()
+		entries := make([]string, len(keys))
+		for idx, key := range keys {
+			value, _ := m.Get(key)
+			entries[idx] = i.stringifyElement(key, printing) + ": " + i.stringifyElement(value, printing)
+		}
+		return "{" + strings.Join(entries, ", ") + "}"
+	}
 	return fmt.Sprintf("%v", object)
 }
 
+// stringifyElement formats a value nested in a collection, quoting strings so
+// the output reads like the literal that would produce it.
+func (i *Interpreter) stringifyElement(object interface{}, printing map[interface{}]bool) string {
+	if str, ok := object.(string); ok {
+		return strconv.Quote(str)
+	}
+	return i.stringify(object, printing)
+}
+
 func (i *Interpreter) isTruthy(object interface{}) bool {
 	if object == nil {
 		return false
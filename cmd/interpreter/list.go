@@ -0,0 +1,44 @@
+package interpreter
+
+import (
+	"fmt"
+	"math"
+)
+
+// LoxList is the runtime representation of a list. Lists are reference
+// values: assigning one shares the underlying elements.
+type LoxList struct {
+	elements []interface{}
+}
+
+func NewLoxList(elements []interface{}) *LoxList {
+	return &LoxList{elements: elements}
+}
+
+func (l *LoxList) Get(index interface{}) (interface{}, error) {
+	idx, err := l.checkIndex(index)
+	if err != nil {
+		return nil, err
+	}
+	return l.elements[idx], nil
+}
+
+func (l *LoxList) Set(index interface{}, value interface{}) error {
+	idx, err := l.checkIndex(index)
+	if err != nil {
+		return err
+	}
+	l.elements[idx] = value
+	return nil
+}
+
+func (l *LoxList) checkIndex(index interface{}) (int, error) {
+	number, ok := index.(float64)
+	if !ok || number != math.Trunc(number) {
+		return 0, fmt.Errorf("List index must be an integer.")
+	}
+	if number < 0 || number >= float64(len(l.elements)) {
+		return 0, fmt.Errorf("List index %d out of bounds for length %d.", int(number), len(l.elements))
+	}
+	return int(number), nil
+}
@@ -0,0 +1,87 @@
+package interpreter
+
+import (
+	"fmt"
+	"sort"
+)
+
+// LoxMap is the runtime representation of a map. Keys are restricted to nil,
+// booleans, numbers and strings, whose Go equality matches Interpreter.isEqual.
+type LoxMap struct {
+	entries map[interface{}]interface{}
+}
+
+func NewLoxMap() *LoxMap {
+	return &LoxMap{entries: make(map[interface{}]interface{})}
+}
+
+// Get reports whether key is present alongside its value. Callers validate
+// the key with checkMapKey first.
+func (m *LoxMap) Get(key interface{}) (interface{}, bool) {
+	value, ok := m.entries[key]
+	return value, ok
+}
+
+func (m *LoxMap) Set(key interface{}, value interface{}) {
+	m.entries[key] = value
+}
+
+func (m *LoxMap) Has(key interface{}) bool {
+	_, ok := m.entries[key]
+	return ok
+}
+
+// Delete removes key and reports whether it was present.
+func (m *LoxMap) Delete(key interface{}) bool {
+	_, ok := m.entries[key]
+	delete(m.entries, key)
+	return ok
+}
+
+// Keys returns the keys in a deterministic order: nil, then booleans, then
+// numbers, then strings, each group sorted by value.
+func (m *LoxMap) Keys() []interface{} {
+	keys := make([]interface{}, 0, len(m.entries))
+	for key := range m.entries {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(a, b int) bool {
+		return lessKey(keys[a], keys[b])
+	})
+	return keys
+}
+
+func checkMapKey(key interface{}) error {
+	switch key.(type) {
+	case nil, bool, float64, string:
+		return nil
+	}
+	return fmt.Errorf("Map keys must be strings, numbers, booleans or nil.")
+}
+
+func keyRank(key interface{}) int {
+	switch key.(type) {
+	case nil:
+		return 0
+	case bool:
+		return 1
+	case float64:
+		return 2
+	}
+	return 3
+}
+
+func lessKey(a, b interface{}) bool {
+	if keyRank(a) != keyRank(b) {
+		return keyRank(a) < keyRank(b)
+	}
+	switch a := a.(type) {
+	case bool:
+		return !a && b.(bool)
+	case float64:
+		return a < b.(float64)
+	case string:
+		return a < b.(string)
+	}
+	return false
+}
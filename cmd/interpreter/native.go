@@ -0,0 +1,145 @@
+package interpreter
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// NativeFn is the Go implementation of a native function. Returning an error
+// raises a runtime error at the call site.
+type NativeFn func(interpreter *Interpreter, arguments []interface{}) (interface{}, error)
+
+type NativeFunction struct {
+	name     string
+	arity    int
+	function NativeFn
+}
+
+func NewNativeFunction(name string, arity int, function NativeFn) *NativeFunction {
+	return &NativeFunction{name: name, arity: arity, function: function}
+}
+
+func (n *NativeFunction) Arity() int {
+	return n.arity
+}
+
+func (n *NativeFunction) Call(interpreter *Interpreter, arguments []interface{}) (interface{}, error) {
+	return n.function(interpreter, arguments)
+}
+
+func (n *NativeFunction) String() string {
+	return "<native fn>"
+}
+
+// RegisterNative defines a native function in the global environment. It must
+// be called before Interpret so scripts can see it.
+func (i *Interpreter) RegisterNative(name string, arity int, function NativeFn) {
+	i.globals.Define(name, NewNativeFunction(name, arity, function))
+}
+
+func (i *Interpreter) registerStandardNatives() {
+	i.RegisterNative("clock", 0, nativeClock)
+	i.RegisterNative("typeof", 1, nativeTypeOf)
+	i.RegisterNative("str", 1, nativeStr)
+	i.RegisterNative("num", 1, nativeNum)
+	i.RegisterNative("len", 1, nativeLen)
+	i.RegisterNative("keys", 1, nativeKeys)
+	i.RegisterNative("has", 2, nativeHas)
+	i.RegisterNative("delete", 2, nativeDelete)
+}
+
+func nativeClock(interpreter *Interpreter, arguments []interface{}) (interface{}, error) {
+	return float64(time.Now().UnixNano()) / float64(time.Second), nil
+}
+
+func nativeTypeOf(interpreter *Interpreter, arguments []interface{}) (interface{}, error) {
+	return typeName(arguments[0]), nil
+}
+
+func nativeStr(interpreter *Interpreter, arguments []interface{}) (interface{}, error) {
+	return interpreter.Stringify(arguments[0]), nil
+}
+
+func nativeNum(interpreter *Interpreter, arguments []interface{}) (interface{}, error) {
+	switch value := arguments[0].(type) {
+	case float64:
+		return value, nil
+	case string:
+		number, err := strconv.ParseFloat(strings.TrimSpace(value), 64)
+		if err != nil {
+			return nil, fmt.Errorf("Can't convert '%s' to a number.", value)
+		}
+		return number, nil
+	}
+	return nil, fmt.Errorf("Can't convert %s to a number.", typeName(arguments[0]))
+}
+
+func nativeLen(interpreter *Interpreter, arguments []interface{}) (interface{}, error) {
+	switch value := arguments[0].(type) {
+	case string:
+		return float64(utf8.RuneCountInString(value)), nil
+	case *LoxList:
+		return float64(len(value.elements)), nil
+	case *LoxMap:
+		return float64(len(value.entries)), nil
+	}
+	return nil, fmt.Errorf("Can't get the length of %s.", typeName(arguments[0]))
+}
+
+func nativeKeys(interpreter *Interpreter, arguments []interface{}) (interface{}, error) {
+	m, ok := arguments[0].(*LoxMap)
+	if !ok {
+		return nil, fmt.Errorf("Can't get the keys of %s.", typeName(arguments[0]))
+	}
+	return NewLoxList(m.Keys()), nil
+}
+
+func nativeHas(interpreter *Interpreter, arguments []interface{}) (interface{}, error) {
+	m, ok := arguments[0].(*LoxMap)
+	if !ok {
+		return nil, fmt.Errorf("Can't look up a key in %s.", typeName(arguments[0]))
+	}
+	if err := checkMapKey(arguments[1]); err != nil {
+		return nil, err
+	}
+	return m.Has(arguments[1]), nil
+}
+
+func nativeDelete(interpreter *Interpreter, arguments []interface{}) (interface{}, error) {
+	m, ok := arguments[0].(*LoxMap)
+	if !ok {
+		return nil, fmt.Errorf("Can't delete a key from %s.", typeName(arguments[0]))
+	}
+	if err := checkMapKey(arguments[1]); err != nil {
+		return nil, err
+	}
+	return m.Delete(arguments[1]), nil
+}
+
+// typeName is the name typeof reports for a runtime value.
+func typeName(value interface{}) string {
+	switch value.(type) {
+	case nil:
+		return "nil"
+	case bool:
+		return "boolean"
+	case float64:
+		return "number"
+	case string:
+		return "string"
+	case *LoxList:
+		return "list"
+	case *LoxMap:
+		return "map"
+	case *LoxClass:
+		return "class"
+	case *LoxInstance:
+		return "instance"
+	case Callable:
+		return "function"
+	}
+	return "unknown"
+}
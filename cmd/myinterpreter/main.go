@@ -6,27 +6,39 @@ import (
 	"os"
 
 	"github.com/codecrafters-io/interpreter-starter-go/cmd/astprinter"
+	"github.com/codecrafters-io/interpreter-starter-go/cmd/diagnostics"
 	"github.com/codecrafters-io/interpreter-starter-go/cmd/interpreter"
 	"github.com/codecrafters-io/interpreter-starter-go/cmd/parser"
+	"github.com/codecrafters-io/interpreter-starter-go/cmd/resolver"
 	"github.com/codecrafters-io/interpreter-starter-go/cmd/scanner"
 )
 
 func main() {
 	if len(os.Args) < 3 {
-		fmt.Fprintln(os.Stderr, "Usage: ./your_program.sh [command] <filename>")
+		fmt.Fprintln(os.Stderr, "Usage: ./your_program.sh [command] [--verbose] <filename>")
 		os.Exit(1)
 	}
 
 	command := os.Args[1]
 	filename := os.Args[2]
 
+	// --verbose makes tokenize also print each token's position.
+	verbose := false
+	if filename == "--verbose" && len(os.Args) > 3 {
+		verbose = true
+		filename = os.Args[3]
+	}
+
 	fileContents, err := os.ReadFile(filename)
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "Error reading file: %v\n", err)
 		os.Exit(1)
 	}
 
-	scanner := scanner.NewScanner(string(fileContents))
+	renderer := diagnostics.NewRenderer(string(fileContents), diagnostics.UseColor(os.Stderr))
+	reporter := diagnostics.NewWriterReporter(os.Stderr, renderer)
+
+	scanner := scanner.NewScanner(string(fileContents), reporter)
 	tokens := scanner.ScanTokens()
 
 	switch command {
@@ -35,16 +47,20 @@ func main() {
 			if token.Literal != nil {
 				if number, ok := token.Literal.(float64); ok {
 					if math.Floor(number) == number {
-						fmt.Printf("%s %s %.1f\n", token.Type, token.Lexeme, number)
+						fmt.Printf("%s %s %.1f", token.Type, token.Lexeme, number)
 					} else {
-						fmt.Printf("%s %s %g\n", token.Type, token.Lexeme, number)
+						fmt.Printf("%s %s %g", token.Type, token.Lexeme, number)
 					}
 				} else {
-					fmt.Printf("%s %s %v\n", token.Type, token.Lexeme, token.Literal)
+					fmt.Printf("%s %s %v", token.Type, token.Lexeme, token.Literal)
 				}
 			} else {
-				fmt.Printf("%s %s null\n", token.Type, token.Lexeme)
+				fmt.Printf("%s %s null", token.Type, token.Lexeme)
 			}
+			if verbose {
+				fmt.Printf(" @ %d:%d offset=%d length=%d", token.StartLine, token.Column, token.Offset, token.Length)
+			}
+			fmt.Println()
 		}
 
 		if scanner.HadError() {
@@ -54,10 +70,9 @@ func main() {
 		if scanner.HadError() {
 			os.Exit(65)
 		}
-		parser := parser.NewParser(tokens)
+		parser := parser.NewParser(tokens, reporter)
 		expression, err := parser.ParseExpression()
 		if err != nil {
-			fmt.Fprintf(os.Stderr, "Error parsing: %v\n", err)
 			os.Exit(65)
 		}
 
@@ -68,47 +83,55 @@ func main() {
 
 		printer := astprinter.NewAstPrinter()
 		fmt.Println(printer.Print(expression))
+	case "ast":
+		// Dump the statement tree of a whole program, including desugared forms.
+		if scanner.HadError() {
+			os.Exit(65)
+		}
+		parser := parser.NewParser(tokens, reporter)
+		statements, err := parser.ParseStatements()
+		if err != nil {
+			os.Exit(65)
+		}
+
+		printer := astprinter.NewAstPrinter()
+		for _, stmt := range statements {
+			fmt.Println(printer.PrintStmt(stmt))
+		}
 	case "evaluate":
-		parser := parser.NewParser(tokens)
+		parser := parser.NewParser(tokens, reporter)
 		expression, err := parser.ParseExpression()
 		if err != nil {
-			fmt.Fprintln(os.Stderr, err.Error())
 			os.Exit(65)
 		}
 
 		// Create the interpreter
-		interp := interpreter.NewInterpreter()
-
-		// Add error handling with defer and recover
-		defer func() {
-			if r := recover(); r != nil {
-				if runtimeErr, ok := r.(*interpreter.RuntimeError); ok {
-					fmt.Fprintln(os.Stderr, runtimeErr.Error())
-				} else {
-					fmt.Fprintf(os.Stderr, "Unexpected error: %v\n", r)
-				}
-				os.Exit(70)
-			}
-		}()
+		interp := interpreter.NewInterpreter(reporter)
 
 		// Evaluate the expression
-		result, _ := interp.Evaluate(expression)
+		result, err := interp.Evaluate(expression)
+		if err != nil {
+			// Evaluate has already reported the error.
+			os.Exit(70)
+		}
 		fmt.Println(interp.Stringify(result))
-		os.Exit(0)
 	case "run":
 		if scanner.HadError() {
 			os.Exit(65)
 		}
-		parser := parser.NewParser(tokens)
+		parser := parser.NewParser(tokens, reporter)
 		statements, err := parser.ParseStatements()
 		if err != nil {
-			fmt.Fprintln(os.Stderr, err.Error())
 			os.Exit(65)
 		}
 
-		interpreter := interpreter.NewInterpreter()
-		interpreter.Interpret(statements)
-		if interpreter.HadRuntimeError {
+		interpreter := interpreter.NewInterpreter(reporter)
+		resolver := resolver.NewResolver(interpreter, reporter)
+		if err := resolver.Resolve(statements); err != nil {
+			os.Exit(65)
+		}
+
+		if err := interpreter.Interpret(statements); err != nil {
 			os.Exit(70)
 		}
 	default:
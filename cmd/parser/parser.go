@@ -3,13 +3,18 @@ package parser
 import (
 	"fmt"
 
+	"github.com/codecrafters-io/interpreter-starter-go/cmd/diagnostics"
 	"github.com/codecrafters-io/interpreter-starter-go/cmd/scanner"
 )
 
 type Parser struct {
-	tokens  []scanner.Token
-	current int
-	errors  []error
+	tokens   []scanner.Token
+	current  int
+	reporter diagnostics.Reporter
+	errors   diagnostics.Errors
+	// loops holds the label of every loop enclosing the current statement,
+	// innermost last. Unlabeled loops are recorded as "".
+	loops []string
 }
 
 type Expr interface {
@@ -78,6 +83,20 @@ type ExprVisitor interface {
 	VisitBinaryExpr(expr *Binary) interface{}
 	VisitVariableExpr(expr *Variable) interface{}
 	VisitAssignExpr(expr *Assign) interface{}
+	VisitLogicalExpr(expr *Logical) interface{}
+	VisitCallExpr(expr *Call) interface{}
+	VisitGetExpr(expr *Get) interface{}
+	VisitSetExpr(expr *Set) interface{}
+	VisitThisExpr(expr *This) interface{}
+	VisitSuperExpr(expr *Super) interface{}
+	VisitListExpr(expr *List) interface{}
+	VisitMapExpr(expr *Map) interface{}
+	VisitInterpolationExpr(expr *Interpolation) interface{}
+	VisitIndexExpr(expr *Index) interface{}
+	VisitIndexSetExpr(expr *IndexSet) interface{}
+	VisitCompoundAssignExpr(expr *CompoundAssign) interface{}
+	VisitIncrementExpr(expr *Increment) interface{}
+	VisitConditionalExpr(expr *Conditional) interface{}
 }
 
 type StmtVisitor interface {
@@ -85,6 +104,13 @@ type StmtVisitor interface {
 	VisitExpressionStmt(stmt *ExpressionStmt) interface{}
 	VisitVarStmt(stmt *VarStmt) interface{}
 	VisitBlockStmt(stmt *BlockStmt) interface{}
+	VisitIfStmt(stmt *IfStmt) interface{}
+	VisitWhileStmt(stmt *WhileStmt) interface{}
+	VisitBreakStmt(stmt *BreakStmt) interface{}
+	VisitContinueStmt(stmt *ContinueStmt) interface{}
+	VisitFunctionStmt(stmt *FunctionStmt) interface{}
+	VisitReturnStmt(stmt *ReturnStmt) interface{}
+	VisitClassStmt(stmt *ClassStmt) interface{}
 }
 
 type Variable struct {
@@ -104,6 +130,148 @@ func (a *Assign) Accept(visitor ExprVisitor) interface{} {
 	return visitor.VisitAssignExpr(a)
 }
 
+type Logical struct {
+	Left     Expr
+	Operator scanner.Token
+	Right    Expr
+}
+
+func (l *Logical) Accept(visitor ExprVisitor) interface{} {
+	return visitor.VisitLogicalExpr(l)
+}
+
+type Call struct {
+	Callee    Expr
+	Paren     scanner.Token
+	Arguments []Expr
+}
+
+func (c *Call) Accept(visitor ExprVisitor) interface{} {
+	return visitor.VisitCallExpr(c)
+}
+
+type Get struct {
+	Object Expr
+	Name   scanner.Token
+}
+
+func (g *Get) Accept(visitor ExprVisitor) interface{} {
+	return visitor.VisitGetExpr(g)
+}
+
+type Set struct {
+	Object Expr
+	Name   scanner.Token
+	Value  Expr
+}
+
+func (s *Set) Accept(visitor ExprVisitor) interface{} {
+	return visitor.VisitSetExpr(s)
+}
+
+type This struct {
+	Keyword scanner.Token
+}
+
+func (t *This) Accept(visitor ExprVisitor) interface{} {
+	return visitor.VisitThisExpr(t)
+}
+
+type Super struct {
+	Keyword scanner.Token
+	Method  scanner.Token
+}
+
+func (s *Super) Accept(visitor ExprVisitor) interface{} {
+	return visitor.VisitSuperExpr(s)
+}
+
+type List struct {
+	Bracket  scanner.Token
+	Elements []Expr
+}
+
+func (l *List) Accept(visitor ExprVisitor) interface{} {
+	return visitor.VisitListExpr(l)
+}
+
+// Map is a map literal. Keys and Values are parallel slices in source order.
+type Map struct {
+	Brace  scanner.Token
+	Keys   []Expr
+	Values []Expr
+}
+
+func (m *Map) Accept(visitor ExprVisitor) interface{} {
+	return visitor.VisitMapExpr(m)
+}
+
+// Interpolation is a string literal with embedded expressions. Parts holds
+// the string fragments as Literals interleaved with the expressions.
+type Interpolation struct {
+	Parts []Expr
+}
+
+func (i *Interpolation) Accept(visitor ExprVisitor) interface{} {
+	return visitor.VisitInterpolationExpr(i)
+}
+
+// Index is a subscript expression such as xs[i]. Bracket is the closing
+// bracket and is used to report runtime errors.
+type Index struct {
+	Object  Expr
+	Bracket scanner.Token
+	Index   Expr
+}
+
+func (i *Index) Accept(visitor ExprVisitor) interface{} {
+	return visitor.VisitIndexExpr(i)
+}
+
+type IndexSet struct {
+	Object  Expr
+	Bracket scanner.Token
+	Index   Expr
+	Value   Expr
+}
+
+func (i *IndexSet) Accept(visitor ExprVisitor) interface{} {
+	return visitor.VisitIndexSetExpr(i)
+}
+
+// CompoundAssign is an assignment such as x += 1. Target is a Variable, Get
+// or Index expression; its object and index are evaluated only once.
+type CompoundAssign struct {
+	Target   Expr
+	Operator scanner.Token
+	Value    Expr
+}
+
+func (c *CompoundAssign) Accept(visitor ExprVisitor) interface{} {
+	return visitor.VisitCompoundAssignExpr(c)
+}
+
+// Increment is a prefix or postfix ++ or -- applied to an assignable Target.
+type Increment struct {
+	Target   Expr
+	Operator scanner.Token
+	Prefix   bool
+}
+
+func (i *Increment) Accept(visitor ExprVisitor) interface{} {
+	return visitor.VisitIncrementExpr(i)
+}
+
+type Conditional struct {
+	Condition  Expr
+	ThenBranch Expr
+	ElseBranch Expr
+}
+
+func (c *Conditional) Accept(visitor ExprVisitor) interface{} {
+	return visitor.VisitConditionalExpr(c)
+}
+
 type VarStmt struct {
 	Name        scanner.Token
 	Initializer Expr
@@ -113,8 +281,40 @@ func (v *VarStmt) Accept(visitor StmtVisitor) interface{} {
 	return visitor.VisitVarStmt(v)
 }
 
-func NewParser(tokens []scanner.Token) *Parser {
-	return &Parser{tokens: tokens, current: 0}
+type FunctionStmt struct {
+	Name   scanner.Token
+	Params []scanner.Token
+	Body   []Stmt
+}
+
+func (f *FunctionStmt) Accept(visitor StmtVisitor) interface{} {
+	return visitor.VisitFunctionStmt(f)
+}
+
+type ReturnStmt struct {
+	Keyword scanner.Token
+	Value   Expr
+}
+
+func (r *ReturnStmt) Accept(visitor StmtVisitor) interface{} {
+	return visitor.VisitReturnStmt(r)
+}
+
+type ClassStmt struct {
+	Name       scanner.Token
+	Superclass *Variable
+	Methods    []*FunctionStmt
+}
+
+func (c *ClassStmt) Accept(visitor StmtVisitor) interface{} {
+	return visitor.VisitClassStmt(c)
+}
+
+// maxArguments is the most parameters or arguments a function may have.
+const maxArguments = 255
+
+func NewParser(tokens []scanner.Token, reporter diagnostics.Reporter) *Parser {
+	return &Parser{tokens: tokens, current: 0, reporter: reporter}
 }
 
 func (p *Parser) Parse() (Expr, []Stmt, error) {
@@ -165,32 +365,52 @@ func (p *Parser) ParseStatements() ([]Stmt, error) {
 		statements = append(statements, stmt)
 	}
 
+	// Every error has already gone to the reporter; returning them too lets
+	// callers tell that parsing failed.
 	if len(p.errors) > 0 {
-		errorMessages := ""
-		for _, err := range p.errors {
-			errorMessages += err.Error() + "\n"
-		}
-		return statements, fmt.Errorf(errorMessages)
+		return statements, p.errors
 	}
 
 	return statements, nil
 }
 
 func (p *Parser) error(token scanner.Token, message string) error {
-	var errMsg string
-	if token.Type == scanner.EOF {
-		errMsg = fmt.Sprintf("[line %d] Error at end: %s", token.Line, message)
-	} else {
-		errMsg = fmt.Sprintf("[line %d] Error at '%s': %s", token.Line, token.Lexeme, message)
+	return p.errorWithHelp(token, message, "")
+}
+
+func (p *Parser) errorWithHelp(token scanner.Token, message, help string) error {
+	err := &diagnostics.Diagnostic{
+		Kind:    diagnostics.KindStatic,
+		Span:    token.Span(),
+		Where:   Where(token),
+		Message: message,
+		Help:    help,
 	}
-	err := fmt.Errorf(errMsg)
+	p.reporter.Report(err)
 	p.errors = append(p.errors, err)
-
-	// fmt.Fprintln(os.Stderr, errMsg)
 	return err
 }
 
+// Where describes token for a static error message: " at 'x'", or " at end"
+// for EOF.
+func Where(token scanner.Token) string {
+	if token.Type == scanner.EOF {
+		return " at end"
+	}
+	return fmt.Sprintf(" at '%s'", token.Lexeme)
+}
+
 func (p *Parser) declaration() (Stmt, error) {
+	if p.match(scanner.CLASS) {
+		return p.classDeclaration()
+	}
+	if p.match(scanner.FUN) {
+		function, err := p.function("function")
+		if err != nil {
+			return nil, err
+		}
+		return function, nil
+	}
 	if p.match(scanner.VAR) {
 		return p.varDeclaration()
 	}
@@ -198,6 +418,93 @@ func (p *Parser) declaration() (Stmt, error) {
 	return p.statement()
 }
 
+func (p *Parser) classDeclaration() (Stmt, error) {
+	name, err := p.consume(scanner.IDENTIFIER, "Expect class name.")
+	if err != nil {
+		return nil, err
+	}
+
+	var superclass *Variable
+	if p.match(scanner.LESS) {
+		superclassName, err := p.consume(scanner.IDENTIFIER, "Expect superclass name.")
+		if err != nil {
+			return nil, err
+		}
+		superclass = &Variable{Name: superclassName}
+	}
+
+	_, err = p.consume(scanner.LEFT_BRACE, "Expect '{' before class body.")
+	if err != nil {
+		return nil, err
+	}
+
+	var methods []*FunctionStmt
+	for !p.check(scanner.RIGHT_BRACE) && !p.isAtEnd() {
+		method, err := p.function("method")
+		if err != nil {
+			return nil, err
+		}
+		methods = append(methods, method)
+	}
+
+	_, err = p.consume(scanner.RIGHT_BRACE, "Expect '}' after class body.")
+	if err != nil {
+		return nil, err
+	}
+
+	return &ClassStmt{Name: name, Superclass: superclass, Methods: methods}, nil
+}
+
+func (p *Parser) function(kind string) (*FunctionStmt, error) {
+	name, err := p.consume(scanner.IDENTIFIER, fmt.Sprintf("Expect %s name.", kind))
+	if err != nil {
+		return nil, err
+	}
+	_, err = p.consume(scanner.LEFT_PAREN, fmt.Sprintf("Expect '(' after %s name.", kind))
+	if err != nil {
+		return nil, err
+	}
+
+	var params []scanner.Token
+	if !p.check(scanner.RIGHT_PAREN) {
+		for {
+			if len(params) >= maxArguments {
+				// Report but keep parsing; the parser isn't confused.
+				p.error(p.peek(), fmt.Sprintf("Can't have more than %d parameters.", maxArguments))
+			}
+			param, err := p.consume(scanner.IDENTIFIER, "Expect parameter name.")
+			if err != nil {
+				return nil, err
+			}
+			params = append(params, param)
+			if !p.match(scanner.COMMA) {
+				break
+			}
+		}
+	}
+	_, err = p.consume(scanner.RIGHT_PAREN, "Expect ')' after parameters.")
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = p.consume(scanner.LEFT_BRACE, fmt.Sprintf("Expect '{' before %s body.", kind))
+	if err != nil {
+		return nil, err
+	}
+
+	// Loops around the declaration don't extend into its body.
+	enclosingLoops := p.loops
+	p.loops = nil
+	defer func() { p.loops = enclosingLoops }()
+
+	body, err := p.block()
+	if err != nil {
+		return nil, err
+	}
+
+	return &FunctionStmt{Name: name, Params: params, Body: body}, nil
+}
+
 func (p *Parser) varDeclaration() (Stmt, error) {
 	name, err := p.consume(scanner.IDENTIFIER, "Expect variable name.")
 	if err != nil {
@@ -245,6 +552,47 @@ func (b *BlockStmt) Accept(visitor StmtVisitor) interface{} {
 	return visitor.VisitBlockStmt(b)
 }
 
+type IfStmt struct {
+	Condition  Expr
+	ThenBranch Stmt
+	ElseBranch Stmt
+}
+
+func (i *IfStmt) Accept(visitor StmtVisitor) interface{} {
+	return visitor.VisitIfStmt(i)
+}
+
+type WhileStmt struct {
+	Label     *scanner.Token
+	Condition Expr
+	Body      Stmt
+	// Increment is only set for desugared for loops. It runs after the body,
+	// including when the body is left through continue.
+	Increment Expr
+}
+
+func (w *WhileStmt) Accept(visitor StmtVisitor) interface{} {
+	return visitor.VisitWhileStmt(w)
+}
+
+type BreakStmt struct {
+	Keyword scanner.Token
+	Label   *scanner.Token
+}
+
+func (b *BreakStmt) Accept(visitor StmtVisitor) interface{} {
+	return visitor.VisitBreakStmt(b)
+}
+
+type ContinueStmt struct {
+	Keyword scanner.Token
+	Label   *scanner.Token
+}
+
+func (c *ContinueStmt) Accept(visitor StmtVisitor) interface{} {
+	return visitor.VisitContinueStmt(c)
+}
+
 func (p *Parser) block() ([]Stmt, error) {
 	var statements []Stmt
 
@@ -266,9 +614,30 @@ func (p *Parser) block() ([]Stmt, error) {
 }
 
 func (p *Parser) statement() (Stmt, error) {
+	if p.check(scanner.IDENTIFIER) && p.peekNext().Type == scanner.COLON {
+		return p.labeledStatement()
+	}
+	if p.match(scanner.BREAK) {
+		return p.breakStatement()
+	}
+	if p.match(scanner.CONTINUE) {
+		return p.continueStatement()
+	}
+	if p.match(scanner.FOR) {
+		return p.forStatement(nil)
+	}
+	if p.match(scanner.IF) {
+		return p.ifStatement()
+	}
 	if p.match(scanner.PRINT) {
 		return p.printStatement()
 	}
+	if p.match(scanner.RETURN) {
+		return p.returnStatement()
+	}
+	if p.match(scanner.WHILE) {
+		return p.whileStatement(nil)
+	}
 	if p.match(scanner.LEFT_BRACE) {
 		statements, err := p.block()
 		if err != nil {
@@ -279,6 +648,102 @@ func (p *Parser) statement() (Stmt, error) {
 	return p.expressionStatement()
 }
 
+// forStatement desugars a C-style for loop into a while loop wrapped in a block,
+// so the initializer gets its own scope:
+//
+//	{ init; while (cond) body; }
+//
+// The increment is kept on the WhileStmt rather than appended to the body so
+// that continue still runs it.
+func (p *Parser) forStatement(label *scanner.Token) (Stmt, error) {
+	_, err := p.consume(scanner.LEFT_PAREN, "Expect '(' after 'for'.")
+	if err != nil {
+		return nil, err
+	}
+
+	var initializer Stmt
+	if p.match(scanner.SEMICOLON) {
+		initializer = nil
+	} else if p.match(scanner.VAR) {
+		initializer, err = p.varDeclaration()
+	} else {
+		initializer, err = p.expressionStatement()
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var condition Expr
+	if !p.check(scanner.SEMICOLON) {
+		condition, err = p.expression()
+		if err != nil {
+			return nil, err
+		}
+	}
+	_, err = p.consume(scanner.SEMICOLON, "Expect ';' after loop condition.")
+	if err != nil {
+		return nil, err
+	}
+
+	var increment Expr
+	if !p.check(scanner.RIGHT_PAREN) {
+		increment, err = p.expression()
+		if err != nil {
+			return nil, err
+		}
+	}
+	_, err = p.consume(scanner.RIGHT_PAREN, "Expect ')' after for clauses.")
+	if err != nil {
+		return nil, err
+	}
+
+	body, err := p.loopBody(label)
+	if err != nil {
+		return nil, err
+	}
+
+	if condition == nil {
+		condition = &Literal{Value: true}
+	}
+	body = &WhileStmt{Label: label, Condition: condition, Body: body, Increment: increment}
+	if initializer != nil {
+		body = &BlockStmt{Statements: []Stmt{initializer, body}}
+	}
+
+	return body, nil
+}
+
+func (p *Parser) ifStatement() (Stmt, error) {
+	_, err := p.consume(scanner.LEFT_PAREN, "Expect '(' after 'if'.")
+	if err != nil {
+		return nil, err
+	}
+	condition, err := p.expression()
+	if err != nil {
+		return nil, err
+	}
+	_, err = p.consume(scanner.RIGHT_PAREN, "Expect ')' after if condition.")
+	if err != nil {
+		return nil, err
+	}
+
+	thenBranch, err := p.statement()
+	if err != nil {
+		return nil, err
+	}
+
+	// The else binds to the nearest if that precedes it.
+	var elseBranch Stmt
+	if p.match(scanner.ELSE) {
+		elseBranch, err = p.statement()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return &IfStmt{Condition: condition, ThenBranch: thenBranch, ElseBranch: elseBranch}, nil
+}
+
 func (p *Parser) printStatement() (Stmt, error) {
 	value, err := p.expression()
 	if err != nil {
@@ -294,6 +759,119 @@ func (p *Parser) printStatement() (Stmt, error) {
 	return &PrintStmt{Expression: value}, nil
 }
 
+func (p *Parser) returnStatement() (Stmt, error) {
+	keyword := p.previous()
+
+	var value Expr
+	if !p.check(scanner.SEMICOLON) {
+		var err error
+		value, err = p.expression()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	_, err := p.consume(scanner.SEMICOLON, "Expect ';' after return value.")
+	if err != nil {
+		return nil, err
+	}
+	return &ReturnStmt{Keyword: keyword, Value: value}, nil
+}
+
+func (p *Parser) whileStatement(label *scanner.Token) (Stmt, error) {
+	_, err := p.consume(scanner.LEFT_PAREN, "Expect '(' after 'while'.")
+	if err != nil {
+		return nil, err
+	}
+	condition, err := p.expression()
+	if err != nil {
+		return nil, err
+	}
+	_, err = p.consume(scanner.RIGHT_PAREN, "Expect ')' after condition.")
+	if err != nil {
+		return nil, err
+	}
+
+	body, err := p.loopBody(label)
+	if err != nil {
+		return nil, err
+	}
+
+	return &WhileStmt{Label: label, Condition: condition, Body: body}, nil
+}
+
+// loopBody parses the body of a loop with the loop pushed onto p.loops so
+// break and continue inside it can be validated.
+func (p *Parser) loopBody(label *scanner.Token) (Stmt, error) {
+	name := ""
+	if label != nil {
+		name = label.Lexeme
+	}
+	p.loops = append(p.loops, name)
+	defer func() { p.loops = p.loops[:len(p.loops)-1] }()
+
+	return p.statement()
+}
+
+func (p *Parser) labeledStatement() (Stmt, error) {
+	label := p.advance()
+	// The ':' after the label.
+	p.advance()
+
+	if p.match(scanner.WHILE) {
+		return p.whileStatement(&label)
+	}
+	if p.match(scanner.FOR) {
+		return p.forStatement(&label)
+	}
+	return nil, p.error(p.peek(), "Expect loop after label.")
+}
+
+func (p *Parser) breakStatement() (Stmt, error) {
+	keyword := p.previous()
+	label, err := p.loopLabel(keyword)
+	if err != nil {
+		return nil, err
+	}
+	_, err = p.consume(scanner.SEMICOLON, "Expect ';' after 'break'.")
+	if err != nil {
+		return nil, err
+	}
+	return &BreakStmt{Keyword: keyword, Label: label}, nil
+}
+
+func (p *Parser) continueStatement() (Stmt, error) {
+	keyword := p.previous()
+	label, err := p.loopLabel(keyword)
+	if err != nil {
+		return nil, err
+	}
+	_, err = p.consume(scanner.SEMICOLON, "Expect ';' after 'continue'.")
+	if err != nil {
+		return nil, err
+	}
+	return &ContinueStmt{Keyword: keyword, Label: label}, nil
+}
+
+// loopLabel checks that a break or continue appears inside a loop and parses
+// its optional target label, which must name an enclosing loop.
+func (p *Parser) loopLabel(keyword scanner.Token) (*scanner.Token, error) {
+	if len(p.loops) == 0 {
+		return nil, p.error(keyword, fmt.Sprintf("Can't use '%s' outside of a loop.", keyword.Lexeme))
+	}
+	if !p.match(scanner.IDENTIFIER) {
+		return nil, nil
+	}
+
+	label := p.previous()
+	for _, name := range p.loops {
+		if name == label.Lexeme {
+			return &label, nil
+		}
+	}
+	return nil, p.error(label, fmt.Sprintf("Undefined label '%s'.", label.Lexeme))
+}
+
 func (p *Parser) expressionStatement() (Stmt, error) {
 	expr, err := p.expression()
 	if err != nil {
@@ -310,7 +888,27 @@ func (p *Parser) expressionStatement() (Stmt, error) {
 }
 
 func (p *Parser) expression() (Expr, error) {
-	return p.assignment()
+	return p.comma()
+}
+
+// comma parses the sequence operator. Argument lists and collection literals
+// call assignment instead, so their commas keep separating elements.
+func (p *Parser) comma() (Expr, error) {
+	expr, err := p.assignment()
+	if err != nil {
+		return nil, err
+	}
+
+	for p.match(scanner.COMMA) {
+		operator := p.previous()
+		right, err := p.assignment()
+		if err != nil {
+			return nil, err
+		}
+		expr = &Binary{Left: expr, Operator: operator, Right: right}
+	}
+
+	return expr, nil
 }
 
 func (p *Parser) equality() (Expr, error) {
@@ -373,7 +971,7 @@ func (p *Parser) factor() (Expr, error) {
 		return nil, err
 	}
 
-	for p.match(scanner.SLASH, scanner.STAR) {
+	for p.match(scanner.SLASH, scanner.STAR, scanner.PERCENT, scanner.TILDE_SLASH) {
 		operator := p.previous()
 		right, err := p.unary()
 		if err != nil {
@@ -386,6 +984,17 @@ func (p *Parser) factor() (Expr, error) {
 }
 
 func (p *Parser) unary() (Expr, error) {
+	if p.match(scanner.PLUS_PLUS, scanner.MINUS_MINUS) {
+		operator := p.previous()
+		target, err := p.unary()
+		if err != nil {
+			return nil, err
+		}
+		if !isAssignable(target) {
+			return nil, p.errorWithHelp(operator, "Invalid assignment target.", assignableHelp)
+		}
+		return &Increment{Target: target, Operator: operator, Prefix: true}, nil
+	}
 	if p.match(scanner.BANG, scanner.MINUS) {
 		operator := p.previous()
 		right, err := p.unary()
@@ -395,11 +1004,111 @@ func (p *Parser) unary() (Expr, error) {
 		return &Unary{Operator: operator, Right: right}, nil
 	}
 
-	return p.primary()
+	return p.power()
+}
+
+// power binds tighter than unary minus, so -2 ** 2 is -(2 ** 2). It is right
+// associative, and its right operand may itself be negated: 2 ** -1.
+func (p *Parser) power() (Expr, error) {
+	expr, err := p.postfix()
+	if err != nil {
+		return nil, err
+	}
+
+	if p.match(scanner.STAR_STAR) {
+		operator := p.previous()
+		right, err := p.unary()
+		if err != nil {
+			return nil, err
+		}
+		expr = &Binary{Left: expr, Operator: operator, Right: right}
+	}
+
+	return expr, nil
+}
+
+func (p *Parser) postfix() (Expr, error) {
+	expr, err := p.call()
+	if err != nil {
+		return nil, err
+	}
+
+	if p.match(scanner.PLUS_PLUS, scanner.MINUS_MINUS) {
+		operator := p.previous()
+		if !isAssignable(expr) {
+			return nil, p.errorWithHelp(operator, "Invalid assignment target.", assignableHelp)
+		}
+		return &Increment{Target: expr, Operator: operator, Prefix: false}, nil
+	}
+
+	return expr, nil
+}
+
+func (p *Parser) call() (Expr, error) {
+	expr, err := p.primary()
+	if err != nil {
+		return nil, err
+	}
+
+	for {
+		if p.match(scanner.LEFT_PAREN) {
+			expr, err = p.finishCall(expr)
+			if err != nil {
+				return nil, err
+			}
+		} else if p.match(scanner.DOT) {
+			name, err := p.consume(scanner.IDENTIFIER, "Expect property name after '.'.")
+			if err != nil {
+				return nil, err
+			}
+			expr = &Get{Object: expr, Name: name}
+		} else if p.match(scanner.LEFT_BRACKET) {
+			index, err := p.expression()
+			if err != nil {
+				return nil, err
+			}
+			bracket, err := p.consume(scanner.RIGHT_BRACKET, "Expect ']' after index.")
+			if err != nil {
+				return nil, err
+			}
+			expr = &Index{Object: expr, Bracket: bracket, Index: index}
+		} else {
+			break
+		}
+	}
+
+	return expr, nil
+}
+
+func (p *Parser) finishCall(callee Expr) (Expr, error) {
+	var arguments []Expr
+	if !p.check(scanner.RIGHT_PAREN) {
+		for {
+			if len(arguments) >= maxArguments {
+				// Report but keep parsing; the parser isn't confused.
+				p.error(p.peek(), fmt.Sprintf("Can't have more than %d arguments.", maxArguments))
+			}
+			argument, err := p.assignment()
+			if err != nil {
+				return nil, err
+			}
+			arguments = append(arguments, argument)
+			if !p.match(scanner.COMMA) {
+				break
+			}
+		}
+	}
+
+	paren, err := p.consume(scanner.RIGHT_PAREN, "Expect ')' after arguments.")
+	if err != nil {
+		return nil, err
+	}
+
+	return &Call{Callee: callee, Paren: paren, Arguments: arguments}, nil
 }
 
 func (p *Parser) assignment() (Expr, error) {
-	expr, err := p.equality()
+	expr, err := p.conditional()
 	if err != nil {
 		return nil, err
 	}
@@ -414,8 +1123,100 @@ func (p *Parser) assignment() (Expr, error) {
 		if variable, ok := expr.(*Variable); ok {
 			return &Assign{Name: variable.Name, Value: value}, nil
 		}
+		if get, ok := expr.(*Get); ok {
+			return &Set{Object: get.Object, Name: get.Name, Value: value}, nil
+		}
+		if index, ok := expr.(*Index); ok {
+			return &IndexSet{Object: index.Object, Bracket: index.Bracket, Index: index.Index, Value: value}, nil
+		}
 
-		return nil, p.error(equals, "Invalid assignment target.")
+		return nil, p.errorWithHelp(equals, "Invalid assignment target.", assignableHelp)
+	}
+
+	if p.match(scanner.PLUS_EQUAL, scanner.MINUS_EQUAL, scanner.STAR_EQUAL, scanner.SLASH_EQUAL) {
+		operator := p.previous()
+		value, err := p.assignment()
+		if err != nil {
+			return nil, err
+		}
+
+		if !isAssignable(expr) {
+			return nil, p.errorWithHelp(operator, "Invalid assignment target.", assignableHelp)
+		}
+		return &CompoundAssign{Target: expr, Operator: operator, Value: value}, nil
+	}
+
+	return expr, nil
+}
+
+const assignableHelp = "Only variables, properties and list or map elements can be assigned to."
+
+func isAssignable(expr Expr) bool {
+	switch expr.(type) {
+	case *Variable, *Get, *Index:
+		return true
+	}
+	return false
+}
+
+// conditional parses cond ? a : b. The middle operand may be any expression;
+// the last nests to the right, so a ? b : c ? d : e groups as a ? b : (c ? d : e).
+func (p *Parser) conditional() (Expr, error) {
+	expr, err := p.or()
+	if err != nil {
+		return nil, err
+	}
+
+	if p.match(scanner.QUESTION) {
+		thenBranch, err := p.expression()
+		if err != nil {
+			return nil, err
+		}
+		_, err = p.consume(scanner.COLON, "Expect ':' after then branch of conditional expression.")
+		if err != nil {
+			return nil, err
+		}
+		elseBranch, err := p.conditional()
+		if err != nil {
+			return nil, err
+		}
+		expr = &Conditional{Condition: expr, ThenBranch: thenBranch, ElseBranch: elseBranch}
+	}
+
+	return expr, nil
+}
+
+func (p *Parser) or() (Expr, error) {
+	expr, err := p.and()
+	if err != nil {
+		return nil, err
+	}
+
+	for p.match(scanner.OR) {
+		operator := p.previous()
+		right, err := p.and()
+		if err != nil {
+			return nil, err
+		}
+		expr = &Logical{Left: expr, Operator: operator, Right: right}
+	}
+
+	return expr, nil
+}
+
+func (p *Parser) and() (Expr, error) {
+	expr, err := p.equality()
+	if err != nil {
+		return nil, err
+	}
+
+	for p.match(scanner.AND) {
+		operator := p.previous()
+		right, err := p.equality()
+		if err != nil {
+			return nil, err
+		}
+		expr = &Logical{Left: expr, Operator: operator, Right: right}
 	}
 
 	return expr, nil
@@ -434,9 +1235,35 @@ func (p *Parser) primary() (Expr, error) {
 	if p.match(scanner.NUMBER, scanner.STRING) {
 		return &Literal{Value: p.previous().Literal}, nil
 	}
+	if p.match(scanner.INTERPOLATION) {
+		return p.interpolation()
+	}
+	if p.match(scanner.SUPER) {
+		keyword := p.previous()
+		_, err := p.consume(scanner.DOT, "Expect '.' after 'super'.")
+		if err != nil {
+			return nil, err
+		}
+		method, err := p.consume(scanner.IDENTIFIER, "Expect superclass method name.")
+		if err != nil {
+			return nil, err
+		}
+		return &Super{Keyword: keyword, Method: method}, nil
+	}
+	if p.match(scanner.THIS) {
+		return &This{Keyword: p.previous()}, nil
+	}
 	if p.match(scanner.IDENTIFIER) {
 		return &Variable{Name: p.previous()}, nil
 	}
+	if p.match(scanner.LEFT_BRACKET) {
+		return p.list()
+	}
+	// Statements starting with '{' are blocks, so a brace that reaches
+	// primary is always in expression position.
+	if p.match(scanner.LEFT_BRACE) {
+		return p.mapLiteral()
+	}
 	if p.match(scanner.LEFT_PAREN) {
 		expr, err := p.expression()
 		if err != nil {
@@ -452,6 +1279,86 @@ func (p *Parser) primary() (Expr, error) {
 	return nil, p.error(p.peek(), "Expect expression.")
 }
 
+func (p *Parser) list() (Expr, error) {
+	var elements []Expr
+	for !p.check(scanner.RIGHT_BRACKET) && !p.isAtEnd() {
+		element, err := p.assignment()
+		if err != nil {
+			return nil, err
+		}
+		elements = append(elements, element)
+		// A trailing comma before the ']' is allowed.
+		if !p.match(scanner.COMMA) {
+			break
+		}
+	}
+
+	bracket, err := p.consume(scanner.RIGHT_BRACKET, "Expect ']' after list elements.")
+	if err != nil {
+		return nil, err
+	}
+	return &List{Bracket: bracket, Elements: elements}, nil
+}
+
+func (p *Parser) mapLiteral() (Expr, error) {
+	brace := p.previous()
+
+	var keys, values []Expr
+	for !p.check(scanner.RIGHT_BRACE) && !p.isAtEnd() {
+		key, err := p.assignment()
+		if err != nil {
+			return nil, err
+		}
+		_, err = p.consume(scanner.COLON, "Expect ':' after map key.")
+		if err != nil {
+			return nil, err
+		}
+		value, err := p.assignment()
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, key)
+		values = append(values, value)
+		// A trailing comma before the '}' is allowed.
+		if !p.match(scanner.COMMA) {
+			break
+		}
+	}
+
+	_, err := p.consume(scanner.RIGHT_BRACE, "Expect '}' after map entries.")
+	if err != nil {
+		return nil, err
+	}
+	return &Map{Brace: brace, Keys: keys, Values: values}, nil
+}
+
+func (p *Parser) interpolation() (Expr, error) {
+	var parts []Expr
+	for {
+		if fragment := p.previous().Literal.(string); fragment != "" {
+			parts = append(parts, &Literal{Value: fragment})
+		}
+
+		expr, err := p.expression()
+		if err != nil {
+			return nil, err
+		}
+		parts = append(parts, expr)
+
+		if p.match(scanner.INTERPOLATION) {
+			continue
+		}
+		_, err = p.consume(scanner.STRING, "Expect end of string interpolation.")
+		if err != nil {
+			return nil, err
+		}
+		if fragment := p.previous().Literal.(string); fragment != "" {
+			parts = append(parts, &Literal{Value: fragment})
+		}
+		return &Interpolation{Parts: parts}, nil
+	}
+}
+
 func (p *Parser) match(types ...scanner.TokenType) bool {
 	for _, t := range types {
 		if p.check(t) {
@@ -484,6 +1391,13 @@ func (p *Parser) peek() scanner.Token {
 	return p.tokens[p.current]
 }
 
+func (p *Parser) peekNext() scanner.Token {
+	if p.current+1 >= len(p.tokens) {
+		return p.tokens[len(p.tokens)-1]
+	}
+	return p.tokens[p.current+1]
+}
+
 func (p *Parser) previous() scanner.Token {
 	return p.tokens[p.current-1]
 }
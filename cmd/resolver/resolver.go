@@ -0,0 +1,395 @@
+package resolver
+
+import (
+	"github.com/codecrafters-io/interpreter-starter-go/cmd/diagnostics"
+	"github.com/codecrafters-io/interpreter-starter-go/cmd/interpreter"
+	"github.com/codecrafters-io/interpreter-starter-go/cmd/parser"
+	"github.com/codecrafters-io/interpreter-starter-go/cmd/scanner"
+)
+
+type FunctionType int
+
+const (
+	FunctionTypeNone FunctionType = iota
+	FunctionTypeFunction
+	FunctionTypeInitializer
+	FunctionTypeMethod
+)
+
+type ClassType int
+
+const (
+	ClassTypeNone ClassType = iota
+	ClassTypeClass
+	ClassTypeSubclass
+)
+
+// Resolver walks the statement tree once before execution and tells the
+// interpreter how many scopes separate each local variable use from its
+// declaration.
+type Resolver struct {
+	interpreter *interpreter.Interpreter
+	// scopes holds one map per enclosing block scope, innermost last. A name
+	// maps to false while it is declared but its initializer is still being
+	// resolved. The global scope is not tracked.
+	scopes          []map[string]bool
+	currentFunction FunctionType
+	currentClass    ClassType
+	reporter        diagnostics.Reporter
+	errors          diagnostics.Errors
+}
+
+func NewResolver(interpreter *interpreter.Interpreter, reporter diagnostics.Reporter) *Resolver {
+	return &Resolver{
+		interpreter:     interpreter,
+		currentFunction: FunctionTypeNone,
+		currentClass:    ClassTypeNone,
+		reporter:        reporter,
+	}
+}
+
+func (r *Resolver) Resolve(statements []parser.Stmt) error {
+	r.resolveStatements(statements)
+
+	if len(r.errors) > 0 {
+		return r.errors
+	}
+
+	return nil
+}
+
+func (r *Resolver) error(token scanner.Token, message string) {
+	r.errorWithHelp(token, message, "")
+}
+
+func (r *Resolver) errorWithHelp(token scanner.Token, message, help string) {
+	err := &diagnostics.Diagnostic{
+		Kind:    diagnostics.KindStatic,
+		Span:    token.Span(),
+		Where:   parser.Where(token),
+		Message: message,
+		Help:    help,
+	}
+	r.reporter.Report(err)
+	r.errors = append(r.errors, err)
+}
+
+func (r *Resolver) resolveStatements(statements []parser.Stmt) {
+	for _, stmt := range statements {
+		r.resolveStmt(stmt)
+	}
+}
+
+func (r *Resolver) resolveStmt(stmt parser.Stmt) {
+	stmt.Accept(r)
+}
+
+func (r *Resolver) resolveExpr(expr parser.Expr) {
+	expr.Accept(r)
+}
+
+func (r *Resolver) resolveFunction(function *parser.FunctionStmt, functionType FunctionType) {
+	enclosingFunction := r.currentFunction
+	r.currentFunction = functionType
+	defer func() { r.currentFunction = enclosingFunction }()
+
+	r.beginScope()
+	for _, param := range function.Params {
+		r.declare(param)
+		r.define(param)
+	}
+	r.resolveStatements(function.Body)
+	r.endScope()
+}
+
+func (r *Resolver) beginScope() {
+	r.scopes = append(r.scopes, make(map[string]bool))
+}
+
+func (r *Resolver) endScope() {
+	r.scopes = r.scopes[:len(r.scopes)-1]
+}
+
+func (r *Resolver) declare(name scanner.Token) {
+	if len(r.scopes) == 0 {
+		return
+	}
+
+	scope := r.scopes[len(r.scopes)-1]
+	if _, ok := scope[name.Lexeme]; ok {
+		r.errorWithHelp(name, "Already a variable with this name in this scope.",
+			"Assign to the existing variable instead, or declare the new one in a nested block.")
+	}
+	scope[name.Lexeme] = false
+}
+
+func (r *Resolver) define(name scanner.Token) {
+	if len(r.scopes) == 0 {
+		return
+	}
+	r.scopes[len(r.scopes)-1][name.Lexeme] = true
+}
+
+func (r *Resolver) resolveLocal(expr parser.Expr, name scanner.Token) {
+	for i := len(r.scopes) - 1; i >= 0; i-- {
+		if _, ok := r.scopes[i][name.Lexeme]; ok {
+			r.interpreter.Resolve(expr, len(r.scopes)-1-i)
+			return
+		}
+	}
+	// Not found in any local scope; assume it is global.
+}
+
+func (r *Resolver) VisitBlockStmt(stmt *parser.BlockStmt) interface{} {
+	r.beginScope()
+	r.resolveStatements(stmt.Statements)
+	r.endScope()
+	return nil
+}
+
+func (r *Resolver) VisitVarStmt(stmt *parser.VarStmt) interface{} {
+	r.declare(stmt.Name)
+	if stmt.Initializer != nil {
+		r.resolveExpr(stmt.Initializer)
+	}
+	r.define(stmt.Name)
+	return nil
+}
+
+func (r *Resolver) VisitClassStmt(stmt *parser.ClassStmt) interface{} {
+	enclosingClass := r.currentClass
+	r.currentClass = ClassTypeClass
+	defer func() { r.currentClass = enclosingClass }()
+
+	r.declare(stmt.Name)
+	r.define(stmt.Name)
+
+	if stmt.Superclass != nil {
+		if stmt.Name.Lexeme == stmt.Superclass.Name.Lexeme {
+			r.error(stmt.Superclass.Name, "A class can't inherit from itself.")
+		}
+
+		r.currentClass = ClassTypeSubclass
+		r.resolveExpr(stmt.Superclass)
+
+		r.beginScope()
+		r.scopes[len(r.scopes)-1]["super"] = true
+	}
+
+	// Methods close over a scope that binds "this".
+	r.beginScope()
+	r.scopes[len(r.scopes)-1]["this"] = true
+
+	for _, method := range stmt.Methods {
+		functionType := FunctionTypeMethod
+		if method.Name.Lexeme == "init" {
+			functionType = FunctionTypeInitializer
+		}
+		r.resolveFunction(method, functionType)
+	}
+
+	r.endScope()
+
+	if stmt.Superclass != nil {
+		r.endScope()
+	}
+	return nil
+}
+
+func (r *Resolver) VisitFunctionStmt(stmt *parser.FunctionStmt) interface{} {
+	// Define eagerly so the function can refer to itself recursively.
+	r.declare(stmt.Name)
+	r.define(stmt.Name)
+
+	r.resolveFunction(stmt, FunctionTypeFunction)
+	return nil
+}
+
+func (r *Resolver) VisitExpressionStmt(stmt *parser.ExpressionStmt) interface{} {
+	r.resolveExpr(stmt.Expression)
+	return nil
+}
+
+func (r *Resolver) VisitIfStmt(stmt *parser.IfStmt) interface{} {
+	r.resolveExpr(stmt.Condition)
+	r.resolveStmt(stmt.ThenBranch)
+	if stmt.ElseBranch != nil {
+		r.resolveStmt(stmt.ElseBranch)
+	}
+	return nil
+}
+
+func (r *Resolver) VisitPrintStmt(stmt *parser.PrintStmt) interface{} {
+	r.resolveExpr(stmt.Expression)
+	return nil
+}
+
+func (r *Resolver) VisitReturnStmt(stmt *parser.ReturnStmt) interface{} {
+	if r.currentFunction == FunctionTypeNone {
+		r.error(stmt.Keyword, "Can't return from top-level code.")
+	}
+
+	if stmt.Value != nil {
+		if r.currentFunction == FunctionTypeInitializer {
+			r.error(stmt.Keyword, "Can't return a value from an initializer.")
+		}
+		r.resolveExpr(stmt.Value)
+	}
+	return nil
+}
+
+func (r *Resolver) VisitWhileStmt(stmt *parser.WhileStmt) interface{} {
+	r.resolveExpr(stmt.Condition)
+	r.resolveStmt(stmt.Body)
+	if stmt.Increment != nil {
+		r.resolveExpr(stmt.Increment)
+	}
+	return nil
+}
+
+func (r *Resolver) VisitBreakStmt(stmt *parser.BreakStmt) interface{} {
+	return nil
+}
+
+func (r *Resolver) VisitContinueStmt(stmt *parser.ContinueStmt) interface{} {
+	return nil
+}
+
+func (r *Resolver) VisitVariableExpr(expr *parser.Variable) interface{} {
+	if len(r.scopes) > 0 {
+		if defined, ok := r.scopes[len(r.scopes)-1][expr.Name.Lexeme]; ok && !defined {
+			r.error(expr.Name, "Can't read local variable in its own initializer.")
+		}
+	}
+
+	r.resolveLocal(expr, expr.Name)
+	return nil
+}
+
+func (r *Resolver) VisitAssignExpr(expr *parser.Assign) interface{} {
+	r.resolveExpr(expr.Value)
+	r.resolveLocal(expr, expr.Name)
+	return nil
+}
+
+func (r *Resolver) VisitCompoundAssignExpr(expr *parser.CompoundAssign) interface{} {
+	r.resolveExpr(expr.Value)
+	r.resolveExpr(expr.Target)
+	return nil
+}
+
+func (r *Resolver) VisitIncrementExpr(expr *parser.Increment) interface{} {
+	r.resolveExpr(expr.Target)
+	return nil
+}
+
+func (r *Resolver) VisitConditionalExpr(expr *parser.Conditional) interface{} {
+	r.resolveExpr(expr.Condition)
+	r.resolveExpr(expr.ThenBranch)
+	r.resolveExpr(expr.ElseBranch)
+	return nil
+}
+
+func (r *Resolver) VisitBinaryExpr(expr *parser.Binary) interface{} {
+	r.resolveExpr(expr.Left)
+	r.resolveExpr(expr.Right)
+	return nil
+}
+
+func (r *Resolver) VisitCallExpr(expr *parser.Call) interface{} {
+	r.resolveExpr(expr.Callee)
+	for _, argument := range expr.Arguments {
+		r.resolveExpr(argument)
+	}
+	return nil
+}
+
+func (r *Resolver) VisitGetExpr(expr *parser.Get) interface{} {
+	// Properties are looked up dynamically, so only the object is resolved.
+	r.resolveExpr(expr.Object)
+	return nil
+}
+
+func (r *Resolver) VisitSetExpr(expr *parser.Set) interface{} {
+	r.resolveExpr(expr.Value)
+	r.resolveExpr(expr.Object)
+	return nil
+}
+
+func (r *Resolver) VisitSuperExpr(expr *parser.Super) interface{} {
+	if r.currentClass == ClassTypeNone {
+		r.error(expr.Keyword, "Can't use 'super' outside of a class.")
+		return nil
+	} else if r.currentClass != ClassTypeSubclass {
+		r.error(expr.Keyword, "Can't use 'super' in a class with no superclass.")
+		return nil
+	}
+
+	r.resolveLocal(expr, expr.Keyword)
+	return nil
+}
+
+func (r *Resolver) VisitThisExpr(expr *parser.This) interface{} {
+	if r.currentClass == ClassTypeNone {
+		r.error(expr.Keyword, "Can't use 'this' outside of a class.")
+		return nil
+	}
+
+	r.resolveLocal(expr, expr.Keyword)
+	return nil
+}
+
+func (r *Resolver) VisitListExpr(expr *parser.List) interface{} {
+	for _, element := range expr.Elements {
+		r.resolveExpr(element)
+	}
+	return nil
+}
+
+func (r *Resolver) VisitMapExpr(expr *parser.Map) interface{} {
+	for idx, key := range expr.Keys {
+		r.resolveExpr(key)
+		r.resolveExpr(expr.Values[idx])
+	}
+	return nil
+}
+
+func (r *Resolver) VisitInterpolationExpr(expr *parser.Interpolation) interface{} {
+	for _, part := range expr.Parts {
+		r.resolveExpr(part)
+	}
+	return nil
+}
+
+func (r *Resolver) VisitIndexExpr(expr *parser.Index) interface{} {
+	r.resolveExpr(expr.Object)
+	r.resolveExpr(expr.Index)
+	return nil
+}
+
+func (r *Resolver) VisitIndexSetExpr(expr *parser.IndexSet) interface{} {
+	r.resolveExpr(expr.Value)
+	r.resolveExpr(expr.Object)
+	r.resolveExpr(expr.Index)
+	return nil
+}
+
+func (r *Resolver) VisitGroupingExpr(expr *parser.Grouping) interface{} {
+	r.resolveExpr(expr.Expression)
+	return nil
+}
+
+func (r *Resolver) VisitLiteralExpr(expr *parser.Literal) interface{} {
+	return nil
+}
+
+func (r *Resolver) VisitLogicalExpr(expr *parser.Logical) interface{} {
+	r.resolveExpr(expr.Left)
+	r.resolveExpr(expr.Right)
+	return nil
+}
+
+func (r *Resolver) VisitUnaryExpr(expr *parser.Unary) interface{} {
+	r.resolveExpr(expr.Right)
+	return nil
+}
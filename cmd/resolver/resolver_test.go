@@ -0,0 +1,143 @@
+package resolver
+
+import (
+	"testing"
+
+	"github.com/codecrafters-io/interpreter-starter-go/cmd/diagnostics"
+	"github.com/codecrafters-io/interpreter-starter-go/cmd/interpreter"
+	"github.com/codecrafters-io/interpreter-starter-go/cmd/parser"
+	"github.com/codecrafters-io/interpreter-starter-go/cmd/scanner"
+)
+
+func parse(t *testing.T, source string) []parser.Stmt {
+	t.Helper()
+	collector := &diagnostics.Collector{}
+	tokens := scanner.NewScanner(source, collector).ScanTokens()
+	statements, err := parser.NewParser(tokens, collector).ParseStatements()
+	if err != nil {
+		t.Fatalf("parse %q: %v", source, err)
+	}
+	return statements
+}
+
+func TestResolver(t *testing.T) {
+	tests := []struct {
+		name   string
+		source string
+		// err is the message of the single static error expected, or empty
+		// if the program should resolve and run, leaving want in result.
+		err  string
+		want interface{}
+	}{
+		{
+			name: "closure keeps the variable it captured",
+			source: `
+				var a = "global";
+				var result = "";
+				{
+					fun showA() { return a; }
+					result = result + showA();
+					var a = "block";
+					result = result + " " + showA();
+				}`,
+			want: "global global",
+		},
+		{
+			name: "inner scope shadows outer",
+			source: `
+				var result;
+				{
+					var a = "outer";
+					{
+						var a = "inner";
+						result = a;
+					}
+				}`,
+			want: "inner",
+		},
+		{
+			name:   "local read in its own initializer",
+			source: `{ var a = "outer"; { var a = a; } }`,
+			err:    "Can't read local variable in its own initializer.",
+		},
+		{
+			name:   "local redeclared in the same scope",
+			source: `{ var a = 1; var a = 2; }`,
+			err:    "Already a variable with this name in this scope.",
+		},
+		{
+			name:   "parameter redeclared",
+			source: `fun f(a, a) {}`,
+			err:    "Already a variable with this name in this scope.",
+		},
+		{
+			name:   "global redeclared",
+			source: `var a = 1; var a = 2; var result = a;`,
+			want:   2.0,
+		},
+		{
+			name:   "return from top level",
+			source: `return 1;`,
+			err:    "Can't return from top-level code.",
+		},
+		{
+			name:   "return a value from an initializer",
+			source: `class A { init() { return 1; } }`,
+			err:    "Can't return a value from an initializer.",
+		},
+		{
+			name:   "this outside of a class",
+			source: `print this;`,
+			err:    "Can't use 'this' outside of a class.",
+		},
+		{
+			name:   "super outside of a class",
+			source: `print super.x;`,
+			err:    "Can't use 'super' outside of a class.",
+		},
+		{
+			name:   "super in a class with no superclass",
+			source: `class A { m() { super.m(); } }`,
+			err:    "Can't use 'super' in a class with no superclass.",
+		},
+		{
+			name:   "class inheriting from itself",
+			source: `class A < A {}`,
+			err:    "A class can't inherit from itself.",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			statements := parse(t, test.source)
+			collector := &diagnostics.Collector{}
+			interp := interpreter.NewInterpreter(collector)
+			err := NewResolver(interp, collector).Resolve(statements)
+
+			if test.err != "" {
+				if err == nil {
+					t.Fatalf("Resolve succeeded, want error %q", test.err)
+				}
+				if len(collector.Diagnostics) != 1 || collector.Diagnostics[0].Message != test.err {
+					t.Fatalf("reported %v, want one error %q", collector.Diagnostics, test.err)
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("Resolve: %v", err)
+			}
+			if err := interp.Interpret(statements); err != nil {
+				t.Fatalf("Interpret: %v", err)
+			}
+			expressions := parse(t, "result;")
+			got, err := interp.Evaluate(expressions[0].(*parser.ExpressionStmt).Expression)
+			if err != nil {
+				t.Fatalf("Evaluate result: %v", err)
+			}
+			if got != test.want {
+				t.Errorf("result = %v, want %v", got, test.want)
+			}
+		})
+	}
+}
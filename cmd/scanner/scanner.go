@@ -2,8 +2,12 @@ package scanner
 
 import (
 	"fmt"
-	"os"
 	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/codecrafters-io/interpreter-starter-go/cmd/diagnostics"
 )
 
 type TokenType string
@@ -13,13 +17,26 @@ const (
 	RIGHT_PAREN   TokenType = "RIGHT_PAREN"
 	LEFT_BRACE    TokenType = "LEFT_BRACE"
 	RIGHT_BRACE   TokenType = "RIGHT_BRACE"
+	LEFT_BRACKET  TokenType = "LEFT_BRACKET"
+	RIGHT_BRACKET TokenType = "RIGHT_BRACKET"
 	COMMA         TokenType = "COMMA"
 	DOT           TokenType = "DOT"
 	MINUS         TokenType = "MINUS"
+	MINUS_MINUS   TokenType = "MINUS_MINUS"
+	MINUS_EQUAL   TokenType = "MINUS_EQUAL"
 	PLUS          TokenType = "PLUS"
+	PLUS_PLUS     TokenType = "PLUS_PLUS"
+	PLUS_EQUAL    TokenType = "PLUS_EQUAL"
 	SEMICOLON     TokenType = "SEMICOLON"
+	COLON         TokenType = "COLON"
+	QUESTION      TokenType = "QUESTION"
 	STAR          TokenType = "STAR"
+	STAR_STAR     TokenType = "STAR_STAR"
+	STAR_EQUAL    TokenType = "STAR_EQUAL"
 	SLASH         TokenType = "SLASH"
+	SLASH_EQUAL   TokenType = "SLASH_EQUAL"
+	TILDE_SLASH   TokenType = "TILDE_SLASH"
+	PERCENT       TokenType = "PERCENT"
 	EQUAL         TokenType = "EQUAL"
 	EQUAL_EQUAL   TokenType = "EQUAL_EQUAL"
 	BANG          TokenType = "BANG"
@@ -29,34 +46,54 @@ const (
 	GREATER       TokenType = "GREATER"
 	GREATER_EQUAL TokenType = "GREATER_EQUAL"
 	STRING        TokenType = "STRING"
+	// INTERPOLATION is a string fragment that ends in "${". Its literal is the
+	// decoded text before the "${"; the embedded expression's tokens follow
+	// it, and the string resumes after the matching "}".
+	INTERPOLATION TokenType = "INTERPOLATION"
 	NUMBER        TokenType = "NUMBER"
 	IDENTIFIER    TokenType = "IDENTIFIER"
 
-	AND    TokenType = "AND"
-	CLASS  TokenType = "CLASS"
-	ELSE   TokenType = "ELSE"
-	FALSE  TokenType = "FALSE"
-	FUN    TokenType = "FUN"
-	FOR    TokenType = "FOR"
-	IF     TokenType = "IF"
-	NIL    TokenType = "NIL"
-	OR     TokenType = "OR"
-	PRINT  TokenType = "PRINT"
-	RETURN TokenType = "RETURN"
-	SUPER  TokenType = "SUPER"
-	THIS   TokenType = "THIS"
-	TRUE   TokenType = "TRUE"
-	VAR    TokenType = "VAR"
-	WHILE  TokenType = "WHILE"
+	AND      TokenType = "AND"
+	BREAK    TokenType = "BREAK"
+	CLASS    TokenType = "CLASS"
+	CONTINUE TokenType = "CONTINUE"
+	ELSE     TokenType = "ELSE"
+	FALSE    TokenType = "FALSE"
+	FUN      TokenType = "FUN"
+	FOR      TokenType = "FOR"
+	IF       TokenType = "IF"
+	NIL      TokenType = "NIL"
+	OR       TokenType = "OR"
+	PRINT    TokenType = "PRINT"
+	RETURN   TokenType = "RETURN"
+	SUPER    TokenType = "SUPER"
+	THIS     TokenType = "THIS"
+	TRUE     TokenType = "TRUE"
+	VAR      TokenType = "VAR"
+	WHILE    TokenType = "WHILE"
 
 	EOF TokenType = "EOF"
 )
 
+// Token is a single lexeme. Line is the line the token ends on, which errors
+// report. StartLine and Column give the position where the token starts, with
+// the column 1-based and counted in runes; they only differ from Line for
+// multi-line strings. Offset and Length give the token's span in the source in
+// bytes.
 type Token struct {
-	Type    TokenType
-	Lexeme  string
-	Literal interface{}
-	Line    int
+	Type      TokenType
+	Lexeme    string
+	Literal   interface{}
+	Line      int
+	StartLine int
+	Column    int
+	Offset    int
+	Length    int
+}
+
+// Span locates the token in the source for diagnostics.
+func (t Token) Span() diagnostics.Span {
+	return diagnostics.Span{Line: t.Line, Column: t.Column, Offset: t.Offset, Length: t.Length}
 }
 
 type Scanner struct {
@@ -66,38 +103,77 @@ type Scanner struct {
 	current  int
 	line     int
 	hadError bool
+	// column is the 1-based rune column of current; startLine and startColumn
+	// are the position of start.
+	column      int
+	startLine   int
+	startColumn int
+	reporter    diagnostics.Reporter
+	// interpolations has one entry per "${" being scanned, innermost last.
+	interpolations []interpolation
+}
+
+// interpolation tracks a "${" whose expression is being scanned.
+type interpolation struct {
+	// braces counts the braces opened inside the expression that are still
+	// unclosed.
+	braces int
+	// opening is the quote that began the string the expression sits in.
+	opening diagnostics.Span
 }
 
 var keywords = map[string]TokenType{
-	"and":    AND,
-	"class":  CLASS,
-	"else":   ELSE,
-	"false":  FALSE,
-	"for":    FOR,
-	"fun":    FUN,
-	"if":     IF,
-	"nil":    NIL,
-	"or":     OR,
-	"print":  PRINT,
-	"return": RETURN,
-	"super":  SUPER,
-	"this":   THIS,
-	"true":   TRUE,
-	"var":    VAR,
-	"while":  WHILE,
-}
-
-func NewScanner(source string) *Scanner {
-	return &Scanner{source: source, line: 1}
+	"and":      AND,
+	"break":    BREAK,
+	"class":    CLASS,
+	"continue": CONTINUE,
+	"else":     ELSE,
+	"false":    FALSE,
+	"for":      FOR,
+	"fun":      FUN,
+	"if":       IF,
+	"nil":      NIL,
+	"or":       OR,
+	"print":    PRINT,
+	"return":   RETURN,
+	"super":    SUPER,
+	"this":     THIS,
+	"true":     TRUE,
+	"var":      VAR,
+	"while":    WHILE,
+}
+
+func NewScanner(source string, reporter diagnostics.Reporter) *Scanner {
+	return &Scanner{
+		source:   source,
+		line:     1,
+		column:   1,
+		reporter: reporter,
+	}
 }
 
 func (s *Scanner) ScanTokens() []Token {
 	for !s.isAtEnd() {
 		s.start = s.current
+		s.startLine = s.line
+		s.startColumn = s.column
 		s.scanToken()
 	}
 
-	s.tokens = append(s.tokens, Token{Type: EOF, Lexeme: "", Literal: nil, Line: s.line})
+	if depth := len(s.interpolations); depth > 0 {
+		s.unterminatedString(s.interpolations[depth-1].opening)
+	}
+
+	s.tokens = append(s.tokens, Token{
+		Type:      EOF,
+		Lexeme:    "",
+		Literal:   nil,
+		Line:      s.line,
+		StartLine: s.line,
+		Column:    s.column,
+		Offset:    len(s.source),
+		Length:    0,
+	})
 	return s.tokens
 }
 
@@ -109,21 +185,69 @@ func (s *Scanner) scanToken() {
 	case ')':
 		s.addToken(RIGHT_PAREN)
 	case '{':
+		if len(s.interpolations) > 0 {
+			s.interpolations[len(s.interpolations)-1].braces++
+		}
 		s.addToken(LEFT_BRACE)
 	case '}':
+		if depth := len(s.interpolations); depth > 0 {
+			if s.interpolations[depth-1].braces == 0 {
+				// This closes the interpolated expression; resume the string.
+				opening := s.interpolations[depth-1].opening
+				s.interpolations = s.interpolations[:depth-1]
+				s.string(opening)
+				return
+			}
+			s.interpolations[depth-1].braces--
+		}
 		s.addToken(RIGHT_BRACE)
+	case '[':
+		s.addToken(LEFT_BRACKET)
+	case ']':
+		s.addToken(RIGHT_BRACKET)
 	case ',':
 		s.addToken(COMMA)
 	case '.':
 		s.addToken(DOT)
 	case '-':
-		s.addToken(MINUS)
+		if s.match('-') {
+			s.addToken(MINUS_MINUS)
+		} else if s.match('=') {
+			s.addToken(MINUS_EQUAL)
+		} else {
+			s.addToken(MINUS)
+		}
 	case '+':
-		s.addToken(PLUS)
+		if s.match('+') {
+			s.addToken(PLUS_PLUS)
+		} else if s.match('=') {
+			s.addToken(PLUS_EQUAL)
+		} else {
+			s.addToken(PLUS)
+		}
 	case ';':
 		s.addToken(SEMICOLON)
+	case ':':
+		s.addToken(COLON)
+	case '?':
+		s.addToken(QUESTION)
 	case '*':
-		s.addToken(STAR)
+		if s.match('*') {
+			s.addToken(STAR_STAR)
+		} else if s.match('=') {
+			s.addToken(STAR_EQUAL)
+		} else {
+			s.addToken(STAR)
+		}
+	case '%':
+		s.addToken(PERCENT)
+	case '~':
+		// "~/" is floor division; "//" is already taken by comments.
+		if s.match('/') {
+			s.addToken(TILDE_SLASH)
+		} else {
+			s.error(fmt.Sprintf("Unexpected character: %c", c))
+		}
 	case '=':
 		if s.match('=') {
 			s.addToken(EQUAL_EQUAL)
@@ -154,26 +278,53 @@ func (s *Scanner) scanToken() {
 			for s.peek() != '\n' && !s.isAtEnd() {
 				s.advance()
 			}
+		} else if s.match('*') {
+			s.blockComment()
+		} else if s.match('=') {
+			s.addToken(SLASH_EQUAL)
 		} else {
 			s.addToken(SLASH)
 		}
 	case '"':
-		s.string()
-	case ' ', '\r', '\t':
-		// Ignore whitespace.
-	case '\n':
-		s.line++
+		s.string(diagnostics.Span{Line: s.startLine, Column: s.startColumn, Offset: s.start, Length: 1})
+	case ' ', '\r', '\t', '\n':
+		// Ignore whitespace; advance has already counted any newline.
 	default:
 		if isDigit(c) {
 			s.number()
 		} else if isAlpha(c) {
 			s.identifier()
+		} else if c == utf8.RuneError && s.current-s.start == 1 {
+			s.error("Invalid UTF-8 encoding.")
 		} else {
 			s.error(fmt.Sprintf("Unexpected character: %c", c))
 		}
 	}
 }
 
+// blockComment skips a /* ... */ comment. Block comments nest, so every "/*"
+// inside needs its own "*/".
+func (s *Scanner) blockComment() {
+	// Point at the opening "/*".
+	opening := diagnostics.Span{Line: s.startLine, Column: s.startColumn, Offset: s.start, Length: 2}
+	depth := 1
+	for depth > 0 {
+		if s.isAtEnd() {
+			s.errorAt(opening, "Unterminated block comment.",
+				"Block comments nest, so every '/*' needs its own '*/'.")
+			return
+		}
+
+		c := s.advance()
+		switch {
+		case c == '/' && s.match('*'):
+			depth++
+		case c == '*' && s.match('/'):
+			depth--
+		}
+	}
+}
+
 func (s *Scanner) identifier() {
 	for isAlphaNumeric(s.peek()) {
 		s.advance()
@@ -187,74 +338,268 @@ func (s *Scanner) identifier() {
 	s.addToken(tokenType)
 }
 
-func (s *Scanner) string() {
+// string scans the rest of a string literal, or the part of one that follows
+// an interpolated expression. opening is the quote that began the literal.
+func (s *Scanner) string(opening diagnostics.Span) {
+	var value strings.Builder
+	valid := true
 	for s.peek() != '"' && !s.isAtEnd() {
-		if s.peek() == '\n' {
-			s.line++
+		if s.peek() == '$' && s.peekNext() == '{' {
+			s.advance()
+			s.advance()
+			s.interpolations = append(s.interpolations, interpolation{opening: opening})
+			if valid {
+				s.addTokenWithLiteral(INTERPOLATION, value.String())
+			}
+			return
+		}
+
+		escapeStart := s.position()
+		c := s.advance()
+		switch c {
+		case '\\':
+			if !s.escape(&value, escapeStart) {
+				valid = false
+			}
+		default:
+			value.WriteRune(c)
 		}
-		s.advance()
 	}
 
 	if s.isAtEnd() {
-		s.error("Unterminated string.")
+		s.unterminatedString(opening)
+		// Any enclosing interpolated strings are unterminated too; one error
+		// is enough.
+		s.interpolations = nil
 		return
 	}
 
 	// The closing ".
 	s.advance()
 
-	// Trim the surrounding quotes.
-	value := s.source[s.start+1 : s.current-1]
-	s.addTokenWithLiteral(STRING, value)
+	if valid {
+		s.addTokenWithLiteral(STRING, value.String())
+	}
 }
 
+// escape decodes the escape sequence following a backslash into value. It
+// reports an error spanning the sequence from start and returns false if the
+// sequence is invalid.
+func (s *Scanner) escape(value *strings.Builder, start diagnostics.Span) bool {
+	if s.isAtEnd() {
+		s.escapeError(start)
+		return false
+	}
+
+	switch c := s.advance(); c {
+	case 'n':
+		value.WriteRune('\n')
+	case 't':
+		value.WriteRune('\t')
+	case 'r':
+		value.WriteRune('\r')
+	case '0':
+		value.WriteRune(0)
+	case '\\', '"', '$':
+		value.WriteRune(c)
+	case 'x':
+		digitsStart := s.current
+		for i := 0; i < 2 && isHexDigit(s.peek()); i++ {
+			s.advance()
+		}
+		if s.current-digitsStart != 2 {
+			s.escapeError(start)
+			return false
+		}
+		code, _ := strconv.ParseUint(s.source[digitsStart:s.current], 16, 8)
+		value.WriteRune(rune(code))
+	case 'u':
+		if !s.match('{') {
+			s.escapeError(start)
+			return false
+		}
+		digitsStart := s.current
+		for isHexDigit(s.peek()) {
+			s.advance()
+		}
+		digits := s.source[digitsStart:s.current]
+		if !s.match('}') || len(digits) == 0 || len(digits) > 6 {
+			s.escapeError(start)
+			return false
+		}
+		code, _ := strconv.ParseUint(digits, 16, 32)
+		if !utf8.ValidRune(rune(code)) {
+			s.escapeError(start)
+			return false
+		}
+		value.WriteRune(rune(code))
+	default:
+		s.escapeError(start)
+		return false
+	}
+	return true
+}
+
+// number scans a numeric literal. Besides plain decimals like 123 and 1.5 it
+// accepts 0x, 0b and 0o prefixed integers, exponents like 1.5e-3 and "_"
+// separators between digits, as in 1_000_000.
 func (s *Scanner) number() {
-	for isDigit(s.peek()) {
-		s.advance()
+	if s.source[s.start] == '0' {
+		switch s.peek() {
+		case 'x', 'X':
+			s.advance()
+			s.radixNumber(16, "hexadecimal", isHexDigit)
+			return
+		case 'b', 'B':
+			s.advance()
+			s.radixNumber(2, "binary", isBinaryDigit)
+			return
+		case 'o', 'O':
+			s.advance()
+			s.radixNumber(8, "octal", isOctalDigit)
+			return
+		}
 	}
 
+	s.digits(isDigit)
+
 	// Look for a fractional part.
 	if s.peek() == '.' && isDigit(s.peekNext()) {
 		// Consume the "."
 		s.advance()
+		s.digits(isDigit)
+	}
 
-		for isDigit(s.peek()) {
-			s.advance()
+	// Look for an exponent.
+	if s.peek() == 'e' || s.peek() == 'E' {
+		s.advance()
+		if !s.match('+') {
+			s.match('-')
 		}
+		if !isDigit(s.peek()) {
+			s.error("Expect digits in number exponent.")
+			return
+		}
+		s.digits(isDigit)
 	}
 
-	value, _ := strconv.ParseFloat(s.source[s.start:s.current], 64)
+	text := s.source[s.start:s.current]
+	if !validSeparators(text, isDigit) {
+		s.error("Digit separators must appear between digits.")
+		return
+	}
+
+	value, err := strconv.ParseFloat(strings.ReplaceAll(text, "_", ""), 64)
+	if err != nil {
+		s.error("Number literal is out of range.")
+		return
+	}
 	s.addTokenWithLiteral(NUMBER, value)
 }
 
-func (s *Scanner) match(expected byte) bool {
+// radixNumber scans the digits of a prefixed integer literal once its prefix
+// has been consumed.
+func (s *Scanner) radixNumber(base int, name string, isRadixDigit func(rune) bool) {
+	prefix := s.source[s.start:s.current]
+	digitsStart := s.current
+	s.digits(isRadixDigit)
+	digits := s.source[digitsStart:s.current]
+
+	// Swallow any trailing letters or digits so a malformed literal is
+	// reported once rather than as a number followed by an identifier.
+	malformed := isAlphaNumeric(s.peek())
+	for isAlphaNumeric(s.peek()) {
+		s.advance()
+	}
+
+	if digits == "" {
+		s.error(fmt.Sprintf("Expect %s digits after '%s'.", name, prefix))
+		return
+	}
+	if malformed {
+		s.error(fmt.Sprintf("Invalid digit in %s literal.", name))
+		return
+	}
+	if !validSeparators(digits, isRadixDigit) {
+		s.error("Digit separators must appear between digits.")
+		return
+	}
+
+	value, err := strconv.ParseUint(strings.ReplaceAll(digits, "_", ""), base, 64)
+	if err != nil {
+		s.error("Number literal is out of range.")
+		return
+	}
+	s.addTokenWithLiteral(NUMBER, float64(value))
+}
+
+// digits consumes a run of digits and "_" separators.
+func (s *Scanner) digits(isRadixDigit func(rune) bool) {
+	for isRadixDigit(s.peek()) || s.peek() == '_' {
+		s.advance()
+	}
+}
+
+// validSeparators reports whether every "_" in text sits between two digits.
+func validSeparators(text string, isRadixDigit func(rune) bool) bool {
+	for i := 0; i < len(text); i++ {
+		if text[i] != '_' {
+			continue
+		}
+		if i == 0 || i == len(text)-1 || !isRadixDigit(rune(text[i-1])) || !isRadixDigit(rune(text[i+1])) {
+			return false
+		}
+	}
+	return true
+}
+
+// The scanner reads the source as UTF-8: current is a byte offset, but match,
+// peek, peekNext and advance all work a whole rune at a time.
+
+func (s *Scanner) match(expected rune) bool {
 	if s.isAtEnd() {
 		return false
 	}
-	if s.source[s.current] != expected {
+	r, _ := utf8.DecodeRuneInString(s.source[s.current:])
+	if r != expected {
 		return false
 	}
-	s.current++
+	s.advance()
 	return true
 }
 
-func (s *Scanner) peek() byte {
+func (s *Scanner) peek() rune {
 	if s.isAtEnd() {
 		return 0
 	}
-	return s.source[s.current]
+	r, _ := utf8.DecodeRuneInString(s.source[s.current:])
+	return r
 }
 
-func (s *Scanner) peekNext() byte {
-	if s.current+1 >= len(s.source) {
+func (s *Scanner) peekNext() rune {
+	if s.isAtEnd() {
 		return 0
 	}
-	return s.source[s.current+1]
+	_, size := utf8.DecodeRuneInString(s.source[s.current:])
+	if s.current+size >= len(s.source) {
+		return 0
+	}
+	r, _ := utf8.DecodeRuneInString(s.source[s.current+size:])
+	return r
 }
 
-func (s *Scanner) advance() byte {
-	s.current++
-	return s.source[s.current-1]
+// advance consumes one rune and keeps line and column up to date, so no other
+// method needs to count newlines.
+func (s *Scanner) advance() rune {
+	r, size := utf8.DecodeRuneInString(s.source[s.current:])
+	s.current += size
+	if r == '\n' {
+		s.line++
+		s.column = 1
+	} else {
+		s.column++
+	}
+	return r
 }
 
 func (s *Scanner) addToken(tokenType TokenType) {
@@ -263,15 +608,48 @@ func (s *Scanner) addToken(tokenType TokenType) {
 
 func (s *Scanner) addTokenWithLiteral(tokenType TokenType, literal interface{}) {
 	text := s.source[s.start:s.current]
-	s.tokens = append(s.tokens, Token{Type: tokenType, Lexeme: text, Literal: literal, Line: s.line})
+	s.tokens = append(s.tokens, Token{
+		Type:      tokenType,
+		Lexeme:    text,
+		Literal:   literal,
+		Line:      s.line,
+		StartLine: s.startLine,
+		Column:    s.startColumn,
+		Offset:    s.start,
+		Length:    s.current - s.start,
+	})
 }
 
 func (s *Scanner) isAtEnd() bool {
 	return s.current >= len(s.source)
 }
 
+func (s *Scanner) escapeError(start diagnostics.Span) {
+	start.Length = s.current - start.Offset
+	s.errorAt(start, "Invalid escape sequence.", "")
+}
+
+// position is a zero-length span at the current rune.
+func (s *Scanner) position() diagnostics.Span {
+	return diagnostics.Span{Line: s.line, Column: s.column, Offset: s.current}
+}
+
+// unterminatedString reports a string that runs to the end of the source,
+// underlining it from its opening quote.
+func (s *Scanner) unterminatedString(opening diagnostics.Span) {
+	opening.Line = s.line
+	opening.Length = s.current - opening.Offset
+	s.errorAt(opening, "Unterminated string.", "")
+}
+
+// error reports a problem with the lexeme scanned so far.
 func (s *Scanner) error(message string) {
-	fmt.Fprintf(os.Stderr, "[line %d] Error: %s\n", s.line, message)
+	span := diagnostics.Span{Line: s.line, Column: s.startColumn, Offset: s.start, Length: s.current - s.start}
+	s.errorAt(span, message, "")
+}
+
+func (s *Scanner) errorAt(span diagnostics.Span, message, help string) {
+	s.reporter.Report(&diagnostics.Diagnostic{Kind: diagnostics.KindStatic, Span: span, Message: message, Help: help})
 	s.hadError = true
 }
 
@@ -279,14 +657,28 @@ func (s *Scanner) HadError() bool {
 	return s.hadError
 }
 
-func isDigit(c byte) bool {
+// isDigit only accepts ASCII digits: number literals are always written with
+// them, even though identifiers may contain other Unicode digits.
+func isDigit(c rune) bool {
 	return c >= '0' && c <= '9'
 }
 
-func isAlpha(c byte) bool {
-	return (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') || c == '_'
+func isBinaryDigit(c rune) bool {
+	return c == '0' || c == '1'
+}
+
+func isOctalDigit(c rune) bool {
+	return c >= '0' && c <= '7'
+}
+
+func isHexDigit(c rune) bool {
+	return isDigit(c) || (c >= 'a' && c <= 'f') || (c >= 'A' && c <= 'F')
+}
+
+func isAlpha(c rune) bool {
+	return unicode.IsLetter(c) || c == '_'
 }
 
-func isAlphaNumeric(c byte) bool {
-	return isAlpha(c) || isDigit(c)
+func isAlphaNumeric(c rune) bool {
+	return isAlpha(c) || unicode.IsDigit(c)
 }